@@ -0,0 +1,244 @@
+package gelf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by AsyncClient.WriteMessage when the queue
+// is at capacity; the message is dropped rather than blocking the
+// caller.
+var ErrQueueFull = errors.New("gelf: async queue is full")
+
+// queuedMessage pairs a Message with the time it was enqueued, so a
+// dequeuing AsyncClient can judge its age against Config.MessageTTL.
+type queuedMessage struct {
+	m          *Message
+	enqueuedAt time.Time
+	size       int
+}
+
+// AsyncClient wraps a Client with a bounded, level-prioritized queue
+// and a background sender (or, with Config.SenderConcurrency, several),
+// so WriteMessage never blocks the caller on the network. Higher-
+// severity messages (lower GELF Level) are always sent ahead of
+// lower-severity ones already waiting, though with more than one
+// sender that ordering is only a preference, not a guarantee, since
+// more than one message can be mid-delivery at once. If the queue is
+// full, the new message displaces the single least severe message
+// queued, or is itself dropped (Stats.Dropped) if nothing queued is
+// less severe.
+type AsyncClient struct {
+	c       *Client
+	pq      *priorityQueue
+	retries *retryQueue
+	notify  chan struct{}
+	closed  chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewAsyncClient starts an AsyncClient around c with a queue capacity
+// of queueSize messages, shared across all severity levels. If
+// c.cfg.MaxRetries is set, it also starts the background retry loop
+// described on Config.MaxRetries.
+func NewAsyncClient(c *Client, queueSize int) *AsyncClient {
+	ac := &AsyncClient{
+		c:      c,
+		pq:     newPriorityQueue(queueSize),
+		notify: make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+
+	senders := c.cfg.SenderConcurrency
+	if senders < 1 {
+		senders = 1
+	}
+	ac.wg.Add(senders)
+	for i := 0; i < senders; i++ {
+		go ac.run()
+	}
+
+	if c.cfg.MaxRetries > 0 {
+		ac.retries = newRetryQueue(c.cfg.RetryQueueSize)
+		ac.wg.Add(1)
+		go ac.runRetries()
+	}
+	return ac
+}
+
+// WriteMessage enqueues m for asynchronous sending, keyed by m.Level
+// for dequeue ordering. It returns immediately; ErrQueueFull means m
+// itself was dropped because the queue was full of messages at least
+// as severe. A full queue may instead silently drop a less severe
+// message already queued, in which case WriteMessage still returns
+// nil for m.
+//
+// If Config.PrecheckMessageSize is set, WriteMessage also validates m's
+// size before enqueueing it, returning *ErrMessageTooLarge synchronously
+// instead of enqueueing a message the background sender can only fail
+// on later, once the caller has already moved on.
+func (ac *AsyncClient) WriteMessage(m *Message) error {
+	b, _ := ac.c.encoder().Encode(m)
+	if ac.c.cfg.PrecheckMessageSize {
+		if err := ac.c.checkMessageSize(b); err != nil {
+			return err
+		}
+	}
+	qm := queuedMessage{m: m, enqueuedAt: ac.c.clock().Now(), size: len(b)}
+
+	pushed, evicted := ac.pq.push(m.Level, qm)
+	if evicted != nil {
+		ac.c.Stats.Dropped.Add(1)
+		ac.c.cfg.notifyDrop(DropReasonQueueOverflow, evicted.m)
+	}
+	if !pushed {
+		ac.c.Stats.Dropped.Add(1)
+		ac.c.cfg.notifyDrop(DropReasonQueueOverflow, m)
+		return ErrQueueFull
+	}
+
+	select {
+	case ac.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// QueueLen returns the number of messages currently buffered, waiting
+// to be sent.
+func (ac *AsyncClient) QueueLen() int {
+	return ac.pq.len()
+}
+
+// QueuedBytes returns the approximate encoded size, in bytes, of
+// every message currently buffered. A shutdown handler can poll this
+// (or QueueLen) alongside Flush-style draining to decide when it's
+// safe to terminate.
+func (ac *AsyncClient) QueuedBytes() int {
+	return int(ac.pq.queuedBytes())
+}
+
+func (ac *AsyncClient) run() {
+	defer ac.wg.Done()
+	for {
+		if qm, ok := ac.pq.pop(); ok {
+			ac.deliver(qm)
+			continue
+		}
+		select {
+		case <-ac.notify:
+		case <-ac.closed:
+			for {
+				qm, ok := ac.pq.pop()
+				if !ok {
+					return
+				}
+				ac.deliver(qm)
+			}
+		}
+	}
+}
+
+func (ac *AsyncClient) deliver(qm queuedMessage) {
+	if ttl := ac.c.cfg.MessageTTL; ttl > 0 && ac.c.clock().Now().Sub(qm.enqueuedAt) > ttl {
+		ac.c.Stats.StaleDropped.Add(1)
+		ac.c.cfg.notifyDrop(DropReasonTTL, qm.m)
+		return
+	}
+	if err := ac.c.WriteMessage(qm.m); err != nil && ac.retries != nil && isTemporaryError(err) {
+		ac.scheduleRetry(qm.m, 1)
+	}
+}
+
+// runRetries periodically re-attempts messages on the retry queue once
+// they come due, until Close/CloseWithTimeout stops it.
+func (ac *AsyncClient) runRetries() {
+	defer ac.wg.Done()
+
+	interval := ac.c.cfg.RetryBackoff
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := ac.c.clock().NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			now := ac.c.clock().Now()
+			for _, it := range ac.retries.due(now) {
+				if err := ac.c.WriteMessage(it.m); err != nil && isTemporaryError(err) {
+					ac.scheduleRetry(it.m, it.attempt+1)
+				}
+			}
+		case <-ac.closed:
+			return
+		}
+	}
+}
+
+// scheduleRetry queues m for retry attempt, or -- once attempt exceeds
+// Config.MaxRetries -- hands it to Config.Fallback instead. A failure
+// to queue (the retry queue is full) is counted as a drop, same as any
+// other message that doesn't make it out.
+func (ac *AsyncClient) scheduleRetry(m *Message, attempt int) {
+	if attempt > ac.c.cfg.MaxRetries {
+		if fb := ac.c.cfg.Fallback; fb != nil {
+			fb(m)
+		}
+		return
+	}
+
+	backoff := ac.c.cfg.RetryBackoff * time.Duration(1<<uint(attempt-1))
+	next := ac.c.clock().Now().Add(backoff)
+	if !ac.retries.push(retryItem{m: m, attempt: attempt, nextAttempt: next}) {
+		ac.c.Stats.Dropped.Add(1)
+		ac.c.cfg.notifyDrop(DropReasonQueueOverflow, m)
+	}
+}
+
+// Close stops accepting new work, drains whatever is already queued,
+// and closes the underlying Client.
+func (ac *AsyncClient) Close() error {
+	close(ac.closed)
+	ac.wg.Wait()
+	return ac.c.Close()
+}
+
+// ErrCloseTimeout is returned by CloseWithTimeout when the deadline
+// passes before the queue finished draining. Undelivered is how many
+// messages were still queued at that point; the socket is closed
+// either way, so those messages are simply lost.
+type ErrCloseTimeout struct {
+	Undelivered int
+}
+
+func (e *ErrCloseTimeout) Error() string {
+	return fmt.Sprintf("gelf: close timed out with %d message(s) undelivered", e.Undelivered)
+}
+
+// CloseWithTimeout behaves like Close, but gives up waiting for the
+// queue to drain once d elapses, returning *ErrCloseTimeout instead of
+// blocking the caller forever. The underlying Client -- and its
+// socket -- is closed either way, which unblocks run() even if it's
+// stuck mid-send, so the drain goroutine doesn't leak past this call.
+func (ac *AsyncClient) CloseWithTimeout(d time.Duration) error {
+	close(ac.closed)
+
+	drained := make(chan struct{})
+	go func() {
+		ac.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return ac.c.Close()
+	case <-time.After(d):
+		undelivered := ac.pq.len()
+		ac.c.Close()
+		return &ErrCloseTimeout{Undelivered: undelivered}
+	}
+}
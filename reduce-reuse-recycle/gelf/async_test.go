@@ -0,0 +1,319 @@
+package gelf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAsyncClientDropsStaleMessages(t *testing.T) {
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		MessageTTL:         time.Second,
+		Clock:              clk,
+	})
+	ac := NewAsyncClient(c, 4)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "stale"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	clk.Advance(2 * time.Second)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "fresh"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := c.Stats.StaleDropped.Load(); got != 1 {
+		t.Fatalf("got StaleDropped=%d, want 1", got)
+	}
+	if len(conn.Packets()) != 1 {
+		t.Fatalf("expected only the fresh message to be sent, got %d packets", len(conn.Packets()))
+	}
+}
+
+// blockingConn wraps memConn so WriteTo stalls until release is
+// closed, letting a test hold a message "in flight" to deterministically
+// fill the async queue behind it.
+type blockingConn struct {
+	memConn
+	release chan struct{}
+}
+
+func (b *blockingConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	<-b.release
+	return b.memConn.WriteTo(p, addr)
+}
+
+func TestAsyncClientDropsWhenQueueFull(t *testing.T) {
+	conn := &blockingConn{release: make(chan struct{})}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	ac := NewAsyncClient(c, 1)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "in-flight"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	// Give run() a moment to dequeue the first message and block in
+	// WriteTo, freeing the queue for exactly one more.
+	time.Sleep(10 * time.Millisecond)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "buffered"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := ac.WriteMessage(&Message{ShortMessage: "overflow"}); err != ErrQueueFull {
+		t.Fatalf("got %v, want ErrQueueFull", err)
+	}
+	if got := c.Stats.Dropped.Load(); got != 1 {
+		t.Fatalf("got Dropped=%d, want 1", got)
+	}
+
+	close(conn.release)
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestAsyncClientSendsHigherSeverityMessagesFirst(t *testing.T) {
+	conn := &blockingConn{release: make(chan struct{})}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	ac := NewAsyncClient(c, 8)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "blocked-in-flight", Level: 7}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // let run() dequeue it and block in WriteTo
+
+	for i := 0; i < 5; i++ {
+		if err := ac.WriteMessage(&Message{ShortMessage: "debug", Level: 7}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	if err := ac.WriteMessage(&Message{ShortMessage: "error", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	close(conn.release)
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 7 {
+		t.Fatalf("got %d packets, want 7", len(packets))
+	}
+	// The first packet is whatever was already in flight when the
+	// error arrived; the second is the error, dequeued ahead of the
+	// four remaining debug messages behind it.
+	var second map[string]interface{}
+	if err := json.Unmarshal(packets[1], &second); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if second["short_message"] != "error" {
+		t.Fatalf("got second packet %v, want the error message sent ahead of the debug backlog", second)
+	}
+}
+
+func TestAsyncClientCloseWithTimeoutReturnsPromptlyOnStuckSocket(t *testing.T) {
+	conn := &blockingConn{release: make(chan struct{})}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	ac := NewAsyncClient(c, 4)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "stuck"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	start := time.Now()
+	err := ac.CloseWithTimeout(20 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("CloseWithTimeout took %v, want it to return promptly", elapsed)
+	}
+
+	var timeoutErr *ErrCloseTimeout
+	if err == nil {
+		t.Fatalf("expected an *ErrCloseTimeout, got nil")
+	}
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("got %v, want *ErrCloseTimeout", err)
+	}
+
+	close(conn.release) // let the stuck WriteTo return so run() can exit
+}
+
+// tempError is a net.Error test double reporting itself as temporary,
+// for exercising the retry path without depending on a real syscall
+// error's Temporary() classification.
+type tempError struct{}
+
+func (tempError) Error() string   { return "simulated temporary outage" }
+func (tempError) Timeout() bool   { return false }
+func (tempError) Temporary() bool { return true }
+
+// flakyConn fails its first `failures` writes with a temporary error,
+// then behaves like memConn, simulating an outage that recovers.
+type flakyConn struct {
+	memConn
+	failures int
+	calls    int
+}
+
+func (f *flakyConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return 0, tempError{}
+	}
+	return f.memConn.WriteTo(p, addr)
+}
+
+func TestAsyncClientRetriesAfterTemporaryOutageThenDelivers(t *testing.T) {
+	conn := &flakyConn{failures: 2}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Clock:              clk,
+		MaxRetries:         3,
+		RetryBackoff:       time.Second,
+		RetryQueueSize:     4,
+	})
+	ac := NewAsyncClient(c, 4)
+	defer ac.Close()
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "outlasts the outage"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // let run() dequeue and fail once
+
+	for i := 0; i < 2; i++ {
+		clk.Advance(time.Second << uint(i))
+		time.Sleep(10 * time.Millisecond) // let runRetries notice and re-attempt
+	}
+
+	if got := len(conn.Packets()); got != 1 {
+		t.Fatalf("got %d packets, want 1 (delivered once the outage cleared)", got)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got, want := fields["short_message"], "outlasts the outage"; got != want {
+		t.Fatalf("got short_message=%v, want %q", got, want)
+	}
+}
+
+func TestAsyncClientFallbackReceivesMessagesThatExhaustRetries(t *testing.T) {
+	conn := &flakyConn{failures: 1000} // never recovers within the test
+	clk := newFakeClock(time.Unix(0, 0))
+	var fellBack []*Message
+	var mu sync.Mutex
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Clock:              clk,
+		MaxRetries:         2,
+		RetryBackoff:       time.Second,
+		RetryQueueSize:     4,
+		Fallback: func(m *Message) {
+			mu.Lock()
+			defer mu.Unlock()
+			fellBack = append(fellBack, m)
+		},
+	})
+	ac := NewAsyncClient(c, 4)
+	defer ac.Close()
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "never recovers"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		clk.Advance(time.Second << uint(i))
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fellBack) != 1 {
+		t.Fatalf("got %d fallback calls, want 1", len(fellBack))
+	}
+	if got, want := fellBack[0].ShortMessage, "never recovers"; got != want {
+		t.Fatalf("got fallback message %q, want %q", got, want)
+	}
+}
+
+func TestAsyncClientQueueLenAndQueuedBytes(t *testing.T) {
+	conn := &blockingConn{release: make(chan struct{})}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	ac := NewAsyncClient(c, 4)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "blocked-in-flight"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // let run() dequeue it and block in WriteTo
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "queued"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if got := ac.QueueLen(); got != 1 {
+		t.Fatalf("got QueueLen=%d, want 1", got)
+	}
+	if got := ac.QueuedBytes(); got <= 0 {
+		t.Fatalf("got QueuedBytes=%d, want > 0", got)
+	}
+
+	close(conn.release)
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := ac.QueuedBytes(); got != 0 {
+		t.Fatalf("got QueuedBytes=%d after drain, want 0", got)
+	}
+}
+
+func TestAsyncClientSenderConcurrencyDeliversEveryMessage(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{SenderConcurrency: 8})
+	ac := NewAsyncClient(c, 256)
+
+	const n = 200
+	for i := 0; i < n; i++ {
+		if err := ac.WriteMessage(&Message{ShortMessage: fmt.Sprintf("msg-%d", i)}); err != nil {
+			t.Fatalf("WriteMessage %d: %v", i, err)
+		}
+	}
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != n {
+		t.Fatalf("got %d packets, want %d", len(packets), n)
+	}
+
+	seen := make(map[string]bool, n)
+	for _, p := range packets {
+		b, err := ReassembleChunks([][]byte{p})
+		if err != nil {
+			t.Fatalf("ReassembleChunks: %v", err)
+		}
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		short, _ := decoded["short_message"].(string)
+		if seen[short] {
+			t.Fatalf("message %q delivered more than once", short)
+		}
+		seen[short] = true
+	}
+	if len(seen) != n {
+		t.Fatalf("got %d distinct messages, want %d", len(seen), n)
+	}
+}
@@ -0,0 +1,87 @@
+package gelf
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchedClient wraps a Client to give the caller explicit control
+// over when packets leave the process: Write/WriteMessage buffer the
+// packets they produce, and FlushBatch sends everything buffered in
+// one batch. This complements AsyncClient, which sends each queued
+// message on its own as soon as a sender is free, for bursty workloads
+// where the caller knows best when to flush.
+type BatchedClient struct {
+	c           *Client
+	maxBuffered int
+
+	mu      sync.Mutex
+	packets [][]byte
+}
+
+// NewBatchedClient wraps c, auto-flushing once maxBuffered packets
+// have accumulated.
+func NewBatchedClient(c *Client, maxBuffered int) *BatchedClient {
+	return &BatchedClient{c: c, maxBuffered: maxBuffered}
+}
+
+// WriteMessage encodes m and buffers the resulting packets, filling
+// in Host and Timestamp as Client.WriteMessage does.
+func (bc *BatchedClient) WriteMessage(m *Message) error {
+	if m.Host == "" {
+		m.Host = bc.c.host
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = bc.c.clock().Now()
+	}
+	m.ensureExtra()
+
+	b, err := bc.c.encoder().Encode(m)
+	if err != nil {
+		bc.c.Stats.Errors.Add(1)
+		return err
+	}
+	_, err = bc.Write(b)
+	return err
+}
+
+// Write buffers the packets produced by compressing and chunking b
+// (already-encoded GELF JSON), auto-flushing if the buffer fills.
+func (bc *BatchedClient) Write(b []byte) (int, error) {
+	packets, err := bc.c.encodePackets(b)
+	if err != nil {
+		bc.c.Stats.Errors.Add(1)
+		return 0, err
+	}
+
+	bc.mu.Lock()
+	bc.packets = append(bc.packets, packets...)
+	full := len(bc.packets) >= bc.maxBuffered
+	bc.mu.Unlock()
+
+	if full {
+		if err := bc.FlushBatch(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// FlushBatch sends every buffered packet in a single batch and clears
+// the buffer. It's a no-op if nothing is buffered.
+func (bc *BatchedClient) FlushBatch() error {
+	bc.mu.Lock()
+	packets := bc.packets
+	bc.packets = nil
+	bc.mu.Unlock()
+
+	if len(packets) == 0 {
+		return nil
+	}
+	if err := bc.c.transportSend(context.Background(), bc.c.destAddr(), packets); err != nil {
+		bc.c.Stats.Errors.Add(1)
+		return err
+	}
+	bc.c.Stats.Sent.Add(1)
+	return nil
+}
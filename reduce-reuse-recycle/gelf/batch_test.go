@@ -0,0 +1,60 @@
+package gelf
+
+import "testing"
+
+func TestBatchedClientBuffersUntilFlush(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	bc := NewBatchedClient(c, 10)
+
+	for i := 0; i < 3; i++ {
+		if err := bc.WriteMessage(&Message{ShortMessage: "x"}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("expected nothing sent before FlushBatch, got %d packets", len(conn.Packets()))
+	}
+
+	if err := bc.FlushBatch(); err != nil {
+		t.Fatalf("FlushBatch: %v", err)
+	}
+	if len(conn.Packets()) != 3 {
+		t.Fatalf("got %d packets, want 3", len(conn.Packets()))
+	}
+}
+
+func TestBatchedClientRecordsByteStats(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	bc := NewBatchedClient(c, 10)
+
+	if err := bc.WriteMessage(&Message{ShortMessage: "x"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := bc.FlushBatch(); err != nil {
+		t.Fatalf("FlushBatch: %v", err)
+	}
+
+	if c.Stats.UncompressedBytes.Load() == 0 {
+		t.Fatalf("expected UncompressedBytes to be recorded for a batched write")
+	}
+	if c.Stats.BytesSent.Load() == 0 {
+		t.Fatalf("expected BytesSent to be recorded for a batched write")
+	}
+}
+
+func TestBatchedClientAutoFlushesWhenFull(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	bc := NewBatchedClient(c, 2)
+
+	for i := 0; i < 2; i++ {
+		if err := bc.WriteMessage(&Message{ShortMessage: "x"}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	if len(conn.Packets()) != 2 {
+		t.Fatalf("expected auto-flush at capacity, got %d packets", len(conn.Packets()))
+	}
+}
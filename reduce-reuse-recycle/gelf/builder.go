@@ -0,0 +1,55 @@
+package gelf
+
+import "time"
+
+// NewMessage returns a Message with ShortMessage and Timestamp set,
+// and fields copied into Extra, for less boilerplate than building a
+// Message literal by hand at every call site. fields isn't validated
+// here -- a key GELF reserves (see ErrReservedField) still passes
+// through NewMessage; it's caught the same way a hand-built Message
+// with a reserved Extra key is, when the Message is actually sent.
+func NewMessage(short string, fields map[string]interface{}) Message {
+	m := Message{ShortMessage: short, Timestamp: time.Now()}
+	if len(fields) > 0 {
+		m.ensureExtra()
+		for k, v := range fields {
+			m.Extra[k] = v
+		}
+	}
+	return m
+}
+
+// WithLevel returns m with Level set, for fluent construction:
+// gelf.NewMessage("disk full", nil).WithLevel(3).
+func (m Message) WithLevel(level int32) Message {
+	m.Level = level
+	return m
+}
+
+// WithHost returns m with Host set, overriding the Client's own host
+// default for this one message.
+func (m Message) WithHost(host string) Message {
+	m.Host = host
+	return m
+}
+
+// WithFullMessage returns m with FullMessage set.
+func (m Message) WithFullMessage(full string) Message {
+	m.FullMessage = full
+	return m
+}
+
+// WithField returns m with one additional Extra entry set. Extra is
+// copied into a fresh map first, even when m already has one, so that
+// mutating the returned Message's Extra -- including via a later
+// WithField call -- never reaches back into the Extra map of an
+// earlier Message this one was built from.
+func (m Message) WithField(key string, val interface{}) Message {
+	extra := make(map[string]interface{}, len(m.Extra)+1)
+	for k, v := range m.Extra {
+		extra[k] = v
+	}
+	extra[key] = val
+	m.Extra = extra
+	return m
+}
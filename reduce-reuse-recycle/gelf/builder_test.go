@@ -0,0 +1,59 @@
+package gelf
+
+import "testing"
+
+func TestNewMessageBuilderChain(t *testing.T) {
+	m := NewMessage("disk full", map[string]interface{}{"mount": "/var"}).
+		WithLevel(3).
+		WithHost("worker-1").
+		WithFullMessage("details here").
+		WithField("retries", 4)
+
+	if m.ShortMessage != "disk full" {
+		t.Fatalf("got ShortMessage=%q, want %q", m.ShortMessage, "disk full")
+	}
+	if m.Level != 3 {
+		t.Fatalf("got Level=%d, want 3", m.Level)
+	}
+	if m.Host != "worker-1" {
+		t.Fatalf("got Host=%q, want %q", m.Host, "worker-1")
+	}
+	if m.FullMessage != "details here" {
+		t.Fatalf("got FullMessage=%q, want %q", m.FullMessage, "details here")
+	}
+	if m.Timestamp.IsZero() {
+		t.Fatalf("expected NewMessage to default Timestamp")
+	}
+	if got, want := m.Extra["mount"], "/var"; got != want {
+		t.Fatalf("got Extra[mount]=%v, want %v", got, want)
+	}
+	if got, want := m.Extra["retries"], 4; got != want {
+		t.Fatalf("got Extra[retries]=%v, want %v", got, want)
+	}
+}
+
+func TestWithFieldDoesNotMutateOriginalExtra(t *testing.T) {
+	base := NewMessage("x", map[string]interface{}{"a": 1})
+	base.WithField("b", 1)
+	base.WithField("c", 2)
+
+	if len(base.Extra) != 1 {
+		t.Fatalf("got base.Extra=%v, want only the original {a:1} untouched", base.Extra)
+	}
+	if _, ok := base.Extra["b"]; ok {
+		t.Fatalf("base.Extra was mutated by a WithField call on a derived copy: %v", base.Extra)
+	}
+}
+
+func TestNewMessageFieldsStillValidatedOnSend(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	m := NewMessage("bad field", map[string]interface{}{"id": "123"})
+	if err := c.WriteMessage(&m); err == nil {
+		t.Fatalf("expected an ErrReservedField for a reserved key passed via NewMessage's fields")
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("expected nothing sent for a message with a reserved field")
+	}
+}
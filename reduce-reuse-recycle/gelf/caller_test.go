@@ -0,0 +1,84 @@
+package gelf
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteMessageExplicitCallSite(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{AddCaller: true, DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "x", File: "wrapper.go", Line: 42}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got["_file"] != "wrapper.go" || got["_line"].(float64) != 42 {
+		t.Fatalf("explicit call site not preserved: %v", got)
+	}
+}
+
+func TestWriteMessageAutoDetectedCallSite(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{AddCaller: true, DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "x"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	file, _ := got["_file"].(string)
+	if !strings.HasSuffix(file, "caller_test.go") {
+		t.Fatalf("expected _file to point at this test file, got %v", got["_file"])
+	}
+	if got["_line"] == nil {
+		t.Fatalf("expected _line to be set, got %v", got)
+	}
+}
+
+func TestWriteContextAutoDetectedCallSite(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{AddCaller: true, DisableCompression: true})
+
+	if err := c.WriteContext(context.Background(), &Message{ShortMessage: "x"}); err != nil {
+		t.Fatalf("WriteContext: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	file, _ := got["_file"].(string)
+	if !strings.HasSuffix(file, "caller_test.go") {
+		t.Fatalf("expected _file to point at this test file, got %v", got["_file"])
+	}
+	if got["_line"] == nil {
+		t.Fatalf("expected _line to be set, got %v", got)
+	}
+}
+
+func TestWriteMessageNoCallerWhenDisabled(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "x"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if _, ok := got["_file"]; ok {
+		t.Fatalf("expected no _file field when AddCaller is disabled, got %v", got)
+	}
+}
@@ -0,0 +1,423 @@
+package gelf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+const (
+	chunkMagic0 = 0x1e
+	chunkMagic1 = 0x0f
+
+	// chunkHeaderLen is 2 magic bytes + 8 id bytes + 1 sequence byte +
+	// 1 count byte.
+	chunkHeaderLen = 12
+
+	// defaultMaxChunkSize keeps a chunk plus its header comfortably
+	// under a 1500-byte Ethernet MTU after IP/UDP overhead.
+	defaultMaxChunkSize = 1420
+
+	// minChunkSize is the floor below which we stop halving on
+	// EMSGSIZE; a path that can't carry this much isn't worth chasing.
+	minChunkSize = 512
+
+	// maxChunks is the GELF protocol's hard limit on chunks per
+	// message.
+	maxChunks = 128
+
+	// defaultAutoCompressThreshold and defaultAutoCompressMaxSize are
+	// Config.AutoCompress's fallback bounds for whichever of
+	// CompressionThreshold/CompressionMaxSize is left at zero: below
+	// 512 bytes gzip's own framing overhead tends to erase the
+	// savings, and above 64KiB a payload is either already dense or
+	// large enough that the CPU cost isn't worth it without the caller
+	// confirming that tradeoff explicitly via CompressionMaxSize.
+	defaultAutoCompressThreshold = 512
+	defaultAutoCompressMaxSize   = 64 << 10
+)
+
+// ErrMessageTooLarge reports that a message would require more GELF
+// chunks than the protocol allows. ChunkCount is how many chunks it
+// would take; Limit is always maxChunks.
+type ErrMessageTooLarge struct {
+	ChunkCount int
+	Limit      int
+}
+
+func (e *ErrMessageTooLarge) Error() string {
+	return fmt.Sprintf("gelf: message requires %d chunks, exceeds limit of %d", e.ChunkCount, e.Limit)
+}
+
+// errPadFinalChunkNeedsNoCompression is returned by dispatchTo when
+// Config.PadFinalChunk is set without Config.DisableCompression --
+// padding would corrupt a gzip stream, so the combination is rejected
+// outright rather than padding only on the messages that happen to be
+// sent uncompressed.
+var errPadFinalChunkNeedsNoCompression = errors.New("gelf: Config.PadFinalChunk requires Config.DisableCompression")
+
+// dispatch compresses b as configured and sends it, chunking as
+// needed, recording the achieved compression ratio in stats.
+func (c *Client) dispatch(ctx context.Context, b []byte, stats *Stats) error {
+	return c.dispatchTo(ctx, c.destAddr(), b, stats)
+}
+
+// dispatchTo is dispatch with the destination overridable, for
+// WriteTo.
+func (c *Client) dispatchTo(ctx context.Context, addr net.Addr, b []byte, stats *Stats) error {
+	return c.dispatchToSkipping(ctx, addr, b, stats, false)
+}
+
+// dispatchToSkipping is dispatchTo with compression forced off
+// regardless of size, for a message marked via
+// Config.CompressionBypassField.
+func (c *Client) dispatchToSkipping(ctx context.Context, addr net.Addr, b []byte, stats *Stats, skipCompression bool) error {
+	if c.cfg.PadFinalChunk && !c.cfg.DisableCompression {
+		return errPadFinalChunkNeedsNoCompression
+	}
+	stats.UncompressedBytes.Add(uint64(len(b)))
+
+	if skipCompression || c.cfg.DisableCompression || c.streamMode || !c.inCompressionWindow(len(b)) {
+		return c.sendCounting(ctx, addr, b, stats)
+	}
+
+	level := c.compressionLevel()
+	if c.cfg.StreamingCompression {
+		return c.sendStreaming(ctx, addr, b, level, stats)
+	}
+
+	cb, err := c.compressAllPooled(b)
+	if err != nil {
+		return err
+	}
+	if !c.meetsMinCompressionBenefit(len(b), len(cb)) {
+		return c.sendCounting(ctx, addr, b, stats)
+	}
+	stats.recordCompressionRatio(len(b), len(cb))
+	return c.sendCounting(ctx, addr, cb, stats)
+}
+
+// sendCounting is send, additionally recording the wire-bytes actually
+// handed to the transport in stats.BytesSent -- the payload as sent,
+// before per-chunk header overhead, so operators can compare it
+// against UncompressedBytes as a compression-effectiveness proxy
+// without re-deriving it from CompressionRatio.
+func (c *Client) sendCounting(ctx context.Context, addr net.Addr, b []byte, stats *Stats) error {
+	stats.BytesSent.Add(uint64(len(b)))
+	return c.send(ctx, addr, b)
+}
+
+// meetsMinCompressionBenefit reports whether compressing uncompressed
+// bytes down to compressed is worth sending compressed, per
+// Config.MinCompressionBenefit: the fraction by which compressed must
+// be smaller than uncompressed. Incompressible input (already-gzipped
+// blobs, random bytes) can come out of gzip larger than it went in;
+// below the configured benefit, callers should send the original
+// bytes instead and rely on Graylog's raw-message detection.
+func (c *Client) meetsMinCompressionBenefit(uncompressed, compressed int) bool {
+	if c.cfg.MinCompressionBenefit <= 0 {
+		return true
+	}
+	return float64(uncompressed-compressed)/float64(uncompressed) >= c.cfg.MinCompressionBenefit
+}
+
+// inCompressionWindow reports whether a payload of size n falls within
+// [CompressionThreshold, CompressionMaxSize], the window in which
+// compression is applied. A zero bound means that side is unbounded,
+// unless Config.AutoCompress is set, in which case a zero bound takes
+// the corresponding defaultAutoCompress* default instead.
+func (c *Client) inCompressionWindow(n int) bool {
+	threshold, maxSize := c.cfg.CompressionThreshold, c.cfg.CompressionMaxSize
+	if c.cfg.AutoCompress {
+		if threshold == 0 {
+			threshold = defaultAutoCompressThreshold
+		}
+		if maxSize == 0 {
+			maxSize = defaultAutoCompressMaxSize
+		}
+	}
+	if threshold > 0 && n < threshold {
+		return false
+	}
+	if maxSize > 0 && n > maxSize {
+		return false
+	}
+	return true
+}
+
+// checkMessageSize reports *ErrMessageTooLarge if b would need more
+// than maxChunks GELF chunks to send. With Config.PrecheckCompression
+// unset, it measures b's raw length against maxChunkSize, which can
+// only overestimate the eventual chunk count -- compression, applied
+// later, never makes a payload bigger by more than gzip's own framing
+// overhead. With Config.PrecheckCompression set, it compresses b
+// first (when it falls in the compression window at all) so the
+// answer matches exactly what dispatchToSkipping will see, at the
+// cost of compressing the message twice: once here, once more when
+// it's actually sent.
+func (c *Client) checkMessageSize(b []byte) error {
+	payload := b
+	if c.cfg.PrecheckCompression && !c.cfg.DisableCompression && c.inCompressionWindow(len(b)) {
+		compressed, err := compressAll(b, c.compressionType(), c.compressionLevel())
+		if err != nil {
+			return err
+		}
+		if c.meetsMinCompressionBenefit(len(b), len(compressed)) {
+			payload = compressed
+		}
+	}
+
+	count := (len(payload) + c.maxChunkSize() - 1) / c.maxChunkSize()
+	if count > maxChunks {
+		return &ErrMessageTooLarge{ChunkCount: count, Limit: maxChunks}
+	}
+	return nil
+}
+
+// send writes b to the client's destination, splitting it into GELF
+// chunks if it doesn't fit in a single datagram. If a write fails with
+// EMSGSIZE, the effective chunk size is halved and the send is retried
+// once with the smaller chunks; the reduced size persists for later
+// messages.
+func (c *Client) send(ctx context.Context, addr net.Addr, b []byte) error {
+	if c.streamMode {
+		c.lastChunks.Store(1)
+		return c.transportSend(ctx, addr, [][]byte{b})
+	}
+
+	maxSize := c.maxChunkSize()
+	var err error
+	if len(b) <= maxSize {
+		c.lastChunks.Store(1)
+		err = c.transportSend(ctx, addr, [][]byte{b})
+	} else {
+		err = c.sendChunked(ctx, addr, b, maxSize)
+	}
+
+	if !isEMSGSIZE(err) {
+		return err
+	}
+
+	if reduced := c.shrinkChunkSize(); reduced {
+		maxSize = c.maxChunkSize()
+		if len(b) <= maxSize {
+			c.lastChunks.Store(1)
+			return c.transportSend(ctx, addr, [][]byte{b})
+		}
+		return c.sendChunked(ctx, addr, b, maxSize)
+	}
+	return err
+}
+
+func (c *Client) maxChunkSize() int {
+	return int(c.chunkSize.Load())
+}
+
+// MaxChunkSize returns the effective per-chunk payload capacity the
+// client will use for its next send: defaultMaxChunkSize unless
+// EMSGSIZE has forced it lower. It's read-only and lock-free, safe to
+// call concurrently with writes.
+func (c *Client) MaxChunkSize() int {
+	return c.maxChunkSize()
+}
+
+// LastChunkCount returns the number of GELF chunks the most recent
+// write was split into. A value greater than 1 means that message
+// exceeded one datagram; callers can use this as cheap telemetry to
+// self-regulate message size.
+func (c *Client) LastChunkCount() int {
+	return int(c.lastChunks.Load())
+}
+
+// shrinkChunkSize halves the effective chunk size, floored at
+// minChunkSize, and reports whether it actually shrank.
+func (c *Client) shrinkChunkSize() bool {
+	for {
+		cur := c.chunkSize.Load()
+		if cur <= minChunkSize {
+			return false
+		}
+		next := cur / 2
+		if next < minChunkSize {
+			next = minChunkSize
+		}
+		if c.chunkSize.CompareAndSwap(cur, next) {
+			return true
+		}
+	}
+}
+
+func isEMSGSIZE(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE)
+}
+
+// notifyChunked calls Config.OnChunked, if set, and sends the
+// Config.AnnotateChunking companion event, if enabled, when a message
+// actually required more than one GELF chunk. Single-packet messages
+// don't need a correlation ID and get neither.
+func (c *Client) notifyChunked(ctx context.Context, id [8]byte, count, compressedBytes int) {
+	if count <= 1 {
+		return
+	}
+	if c.cfg.OnChunked != nil {
+		c.cfg.OnChunked(id, count)
+	}
+	if c.cfg.AnnotateChunking {
+		var discard Stats
+		c.writeMessage(ctx, &Message{
+			ShortMessage: "gelf chunked message",
+			Level:        7, // debug
+			Extra: map[string]interface{}{
+				"_chunk_count":      count,
+				"_compressed_bytes": compressedBytes,
+			},
+		}, &discard)
+	}
+}
+
+// sendStreaming compresses b incrementally and sends the result,
+// chunking if needed. Every produced chunk-sized block is held until
+// compression finishes (the total chunk count must be known before the
+// first chunk header can be written), but no intermediate buffer is
+// sized to the whole compressed payload. If Config.MaxInFlightBytes is
+// set, collection is bounded by compressStreamingBounded instead of
+// running unbounded ahead of it.
+func (c *Client) sendStreaming(ctx context.Context, addr net.Addr, b []byte, level int, stats *Stats) error {
+	maxSize := c.maxChunkSize()
+	kind := c.compressionType()
+
+	var payloads [][]byte
+	var total int
+	collect := func(p []byte) error {
+		payloads = append(payloads, p)
+		total += len(p)
+		return nil
+	}
+
+	var err error
+	if limit := c.cfg.MaxInFlightBytes; limit > 0 {
+		err = compressStreamingBounded(b, kind, level, maxSize, limit, collect)
+	} else {
+		err = compressStreaming(b, kind, level, maxSize, collect)
+	}
+	if err != nil {
+		return err
+	}
+	c.lastChunks.Store(int64(len(payloads)))
+	stats.recordCompressionRatio(len(b), total)
+	stats.BytesSent.Add(uint64(total))
+
+	if len(payloads) == 1 {
+		return c.transportSend(ctx, addr, payloads)
+	}
+	return c.sendPrechunked(ctx, addr, payloads)
+}
+
+// sendPrechunked sends payloads, already split into chunk-sized
+// pieces, as a single GELF chunk group.
+func (c *Client) sendPrechunked(ctx context.Context, addr net.Addr, payloads [][]byte) error {
+	if len(payloads) > maxChunks {
+		return &ErrMessageTooLarge{ChunkCount: len(payloads), Limit: maxChunks}
+	}
+
+	id := c.messageID()
+	total := 0
+	for _, p := range payloads {
+		total += len(p)
+	}
+	c.notifyChunked(ctx, id, len(payloads), total)
+	header := make([]byte, chunkHeaderLen)
+	header[0], header[1] = chunkMagic0, chunkMagic1
+	copy(header[2:10], id[:])
+	header[11] = byte(len(payloads))
+
+	packets := make([][]byte, len(payloads))
+	for seq, p := range payloads {
+		header[10] = byte(seq)
+		packets[seq] = append(append([]byte{}, header...), p...)
+	}
+	return c.transportSend(ctx, addr, packets)
+}
+
+func (c *Client) sendChunked(ctx context.Context, addr net.Addr, b []byte, maxSize int) error {
+	packets, err := c.chunkPackets(ctx, b, maxSize)
+	if err != nil {
+		return err
+	}
+	return c.transportSend(ctx, addr, packets)
+}
+
+// chunkPackets splits b into GELF chunks of at most maxSize bytes of
+// payload each, returning the wire-ready packets without sending
+// them.
+func (c *Client) chunkPackets(ctx context.Context, b []byte, maxSize int) ([][]byte, error) {
+	count := (len(b) + maxSize - 1) / maxSize
+	if count > maxChunks {
+		return nil, &ErrMessageTooLarge{ChunkCount: count, Limit: maxChunks}
+	}
+	c.lastChunks.Store(int64(count))
+
+	id := c.messageID()
+	c.notifyChunked(ctx, id, count, len(b))
+	chunk := make([]byte, chunkHeaderLen+maxSize)
+	chunk[0], chunk[1] = chunkMagic0, chunkMagic1
+	copy(chunk[2:10], id[:])
+	chunk[11] = byte(count)
+
+	packets := make([][]byte, 0, count)
+	for seq := 0; seq < count; seq++ {
+		start := seq * maxSize
+		end := start + maxSize
+		if end > len(b) {
+			end = len(b)
+		}
+
+		chunk[10] = byte(seq)
+		n := copy(chunk[chunkHeaderLen:], b[start:end])
+		if n == 0 {
+			// count is ceil(len(b)/maxSize), so every seq in range
+			// should have data; this only guards against an off-by-one
+			// slipping in above and sending a header-only chunk that
+			// would confuse reassembly.
+			break
+		}
+		if c.cfg.PadFinalChunk && seq == count-1 && n < maxSize {
+			padded := make([]byte, chunkHeaderLen+maxSize)
+			copy(padded, chunk[:chunkHeaderLen+n])
+			for i := chunkHeaderLen + n; i < len(padded); i++ {
+				padded[i] = ' '
+			}
+			packets = append(packets, padded)
+			continue
+		}
+		packets = append(packets, append([]byte{}, chunk[:chunkHeaderLen+n]...))
+	}
+	return packets, nil
+}
+
+// encodePackets compresses b per this client's non-streaming
+// configuration and splits the result into wire-ready GELF chunks,
+// without sending them. Used by BatchedClient, which needs the
+// packets in hand before deciding when to flush.
+func (c *Client) encodePackets(b []byte) ([][]byte, error) {
+	c.Stats.UncompressedBytes.Add(uint64(len(b)))
+
+	payload := b
+	if !c.cfg.DisableCompression && c.inCompressionWindow(len(b)) {
+		cb, err := c.compressAllPooled(b)
+		if err != nil {
+			return nil, err
+		}
+		c.Stats.recordCompressionRatio(len(b), len(cb))
+		payload = cb
+	}
+	c.Stats.BytesSent.Add(uint64(len(payload)))
+
+	maxSize := c.maxChunkSize()
+	if len(payload) <= maxSize {
+		return [][]byte{payload}, nil
+	}
+	return c.chunkPackets(context.Background(), payload, maxSize)
+}
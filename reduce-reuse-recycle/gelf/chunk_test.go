@@ -0,0 +1,419 @@
+package gelf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestClientMessageIDUnique(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+
+	first := c.messageID()
+	second := c.messageID()
+	if first == second {
+		t.Fatalf("expected distinct message IDs, got %v twice", first)
+	}
+}
+
+func TestClientDeterministicProducesIdenticalIDSequences(t *testing.T) {
+	cfg := Config{Deterministic: true, InstanceID: "replay-instance", StartCounter: 41}
+	a := newClient(&memConn{}, testAddr, cfg)
+	b := newClient(&memConn{}, testAddr, cfg)
+
+	for i := 0; i < 5; i++ {
+		idA, idB := a.messageID(), b.messageID()
+		if idA != idB {
+			t.Fatalf("call %d: got ids %x and %x, want identical sequences", i, idA, idB)
+		}
+	}
+}
+
+func TestClientSendChunksLargePayload(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{})
+
+	payload := make([]byte, defaultMaxChunkSize*3)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 3 {
+		t.Fatalf("got %d packets, want 3", len(packets))
+	}
+	assertContiguousChunkSequence(t, packets)
+	for i, p := range packets {
+		if p[0] != chunkMagic0 || p[1] != chunkMagic1 {
+			t.Fatalf("packet %d missing chunk magic", i)
+		}
+		if int(p[10]) != i {
+			t.Fatalf("packet %d has sequence byte %d", i, p[10])
+		}
+		if int(p[11]) != len(packets) {
+			t.Fatalf("packet %d has count byte %d, want %d", i, p[11], len(packets))
+		}
+	}
+}
+
+// TestClientSendSingleDatagramSkipsChunkFraming confirms send's
+// len(b) <= maxSize branch really does send the raw compressed
+// payload with no GELF chunk header: the wire bytes should start with
+// gzip's own magic (0x1f 0x8b), not the chunk magic (0x1e 0x0f), and
+// there should be exactly one packet.
+func TestClientSendSingleDatagramSkipsChunkFraming(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{})
+
+	cb, err := c.compressAllPooled([]byte("small enough to fit in one datagram"))
+	if err != nil {
+		t.Fatalf("compressAllPooled: %v", err)
+	}
+	if err := c.send(context.Background(), testAddr, cb); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1", len(packets))
+	}
+	got := packets[0]
+	if got[0] != 0x1f || got[1] != 0x8b {
+		t.Fatalf("got packet starting %#x %#x, want the gzip magic 0x1f 0x8b", got[0], got[1])
+	}
+	if got[0] == chunkMagic0 && got[1] == chunkMagic1 {
+		t.Fatalf("packet carries a GELF chunk header; single-datagram sends must not be chunked")
+	}
+}
+
+func TestClientSendChunksShareIDAndOrder(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{})
+
+	payload := make([]byte, defaultMaxChunkSize*4+37)
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) == 0 {
+		t.Fatalf("expected at least one packet")
+	}
+
+	id := packets[0][2:10]
+	for i, p := range packets {
+		if int(p[10]) != i {
+			t.Fatalf("packet %d: sequence byte = %d, want %d", i, p[10], i)
+		}
+		if int(p[11]) != len(packets) {
+			t.Fatalf("packet %d: count byte = %d, want %d", i, p[11], len(packets))
+		}
+		if string(p[2:10]) != string(id) {
+			t.Fatalf("packet %d: ID %x does not match first chunk's ID %x", i, p[2:10], id)
+		}
+	}
+}
+
+func BenchmarkClientMessageID(b *testing.B) {
+	c := newClient(&memConn{}, testAddr, Config{})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = c.messageID()
+	}
+}
+
+func TestClientMaxChunkSizeReflectsShrink(t *testing.T) {
+	conn := &mtuConn{threshold: 800}
+	c := newClient(conn, testAddr, Config{})
+
+	if got, want := c.MaxChunkSize(), defaultMaxChunkSize; got != want {
+		t.Fatalf("got %d, want default %d", got, want)
+	}
+
+	payload := make([]byte, 2000)
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if got := c.MaxChunkSize(); got >= defaultMaxChunkSize {
+		t.Fatalf("expected MaxChunkSize to reflect the EMSGSIZE-driven shrink, got %d", got)
+	}
+}
+
+// TestClientSendSkipsChunkFramingAfterEMSGSIZERecovery guards against
+// the EMSGSIZE-retry path in send unconditionally chunking, even when
+// the shrunk chunk size still fits the whole message in one datagram.
+func TestClientSendSkipsChunkFramingAfterEMSGSIZERecovery(t *testing.T) {
+	conn := &emsgsizeOnceConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	payload := make([]byte, 100)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1", len(packets))
+	}
+	if got := packets[0]; got[0] == chunkMagic0 && got[1] == chunkMagic1 {
+		t.Fatalf("packet carries a GELF chunk header; a message that fits in one datagram at the shrunk chunk size must not be chunked")
+	}
+	if len(packets[0]) != len(payload) {
+		t.Fatalf("got packet of %d bytes, want exactly the %d-byte payload with no header", len(packets[0]), len(payload))
+	}
+}
+
+func TestClientLastChunkCountReflectsMostRecentWrite(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.send(context.Background(), testAddr, []byte("small")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got := c.LastChunkCount(); got != 1 {
+		t.Fatalf("got %d, want 1", got)
+	}
+
+	large := make([]byte, defaultMaxChunkSize*3)
+	if err := c.send(context.Background(), testAddr, large); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if got := c.LastChunkCount(); got <= 1 {
+		t.Fatalf("got %d, want >1 for a chunked message", got)
+	}
+}
+
+func TestClientOnChunkedFiresOnlyForMultiChunkMessages(t *testing.T) {
+	var gotID [8]byte
+	var gotCount int
+	calls := 0
+	c := newClient(&memConn{}, testAddr, Config{
+		OnChunked: func(id [8]byte, count int) {
+			calls++
+			gotID = id
+			gotCount = count
+		},
+	})
+
+	if err := c.send(context.Background(), testAddr, []byte("short")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("OnChunked fired for a single-packet message")
+	}
+
+	payload := make([]byte, defaultMaxChunkSize*3)
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("got %d OnChunked calls, want 1", calls)
+	}
+	if gotCount != 3 {
+		t.Fatalf("got count=%d, want 3", gotCount)
+	}
+	if gotID == ([8]byte{}) {
+		t.Fatalf("got zero-value chunk-group ID")
+	}
+}
+
+func TestClientAnnotateChunkingSendsCompanionEventOnlyForChunkedMessages(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, AnnotateChunking: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "short"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if got := len(conn.Packets()); got != 1 {
+		t.Fatalf("got %d packets after a single-packet message, want 1 (no companion event)", got)
+	}
+
+	payload := strings.Repeat("x", defaultMaxChunkSize*3)
+	if err := c.WriteMessage(&Message{ShortMessage: payload}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	// notifyChunked sends the companion event synchronously, as part of
+	// building the original message's chunks, so it reaches conn
+	// before the chunks it's describing do.
+	assertContiguousChunkSequence(t, packets[2:])
+
+	var companion map[string]interface{}
+	if err := json.Unmarshal(packets[1], &companion); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got, want := companion["short_message"], "gelf chunked message"; got != want {
+		t.Fatalf("got short_message=%v, want %q", got, want)
+	}
+	if got, ok := companion["_chunk_count"].(float64); !ok || got <= 1 {
+		t.Fatalf("got _chunk_count=%v, want >1", companion["_chunk_count"])
+	}
+	if got, ok := companion["_compressed_bytes"].(float64); !ok || got <= 0 {
+		t.Fatalf("got _compressed_bytes=%v, want >0", companion["_compressed_bytes"])
+	}
+}
+
+func TestClientSendChunksExactMultipleWithoutExtraChunk(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	const n = 3
+	payload := make([]byte, defaultMaxChunkSize*n)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != n {
+		t.Fatalf("got %d packets for an exact %d-chunk payload, want %d", len(packets), n, n)
+	}
+	assertContiguousChunkSequence(t, packets)
+	for i, p := range packets {
+		if len(p) != chunkHeaderLen+defaultMaxChunkSize {
+			t.Fatalf("packet %d has %d bytes, want a full chunk", i, len(p))
+		}
+	}
+}
+
+func TestChunkPacketsAtChunkSizeBoundaries(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+
+	for n := 1; n <= 5; n++ {
+		base := defaultMaxChunkSize * n
+		for _, size := range []int{base - 1, base, base + 1, base + 700} {
+			size := size
+			t.Run(fmt.Sprintf("n=%d/size=%d", n, size), func(t *testing.T) {
+				payload := make([]byte, size)
+				for i := range payload {
+					payload[i] = byte(i)
+				}
+
+				packets, err := c.chunkPackets(context.Background(), payload, defaultMaxChunkSize)
+				if err != nil {
+					t.Fatalf("chunkPackets: %v", err)
+				}
+
+				wantCount := (size + defaultMaxChunkSize - 1) / defaultMaxChunkSize
+				if len(packets) != wantCount {
+					t.Fatalf("got %d chunks for %d bytes at %d per chunk, want %d", len(packets), size, defaultMaxChunkSize, wantCount)
+				}
+				assertContiguousChunkSequence(t, packets)
+
+				got, err := ReassembleChunks(packets)
+				if err != nil {
+					t.Fatalf("ReassembleChunks: %v", err)
+				}
+				if !bytes.Equal(got, payload) {
+					t.Fatalf("reassembled payload does not match original for size=%d", size)
+				}
+			})
+		}
+	}
+}
+
+func TestMinCompressionBenefitSendsUncompressedWhenNotWorthwhile(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{MinCompressionBenefit: 0.1})
+
+	r := rand.New(rand.NewSource(1))
+	payload := make([]byte, 512)
+	r.Read(payload)
+
+	if err := c.dispatch(context.Background(), payload, &c.Stats); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1", len(packets))
+	}
+	if !bytes.Equal(packets[0], payload) {
+		t.Fatalf("expected the uncompressed payload to be sent as-is")
+	}
+}
+
+func TestPadFinalChunkPadsUncompressedShortFinalChunk(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, PadFinalChunk: true})
+
+	payload := bytes.Repeat([]byte("z"), defaultMaxChunkSize*2+100)
+	if err := c.dispatch(context.Background(), payload, &c.Stats); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 3 {
+		t.Fatalf("got %d packets, want 3", len(packets))
+	}
+	last := packets[len(packets)-1]
+	if len(last) != chunkHeaderLen+defaultMaxChunkSize {
+		t.Fatalf("got final chunk length %d, want %d (padded to max chunk size)", len(last), chunkHeaderLen+defaultMaxChunkSize)
+	}
+
+	got, err := ReassembleChunks(packets)
+	if err != nil {
+		t.Fatalf("ReassembleChunks: %v", err)
+	}
+	wantPadding := defaultMaxChunkSize - 100
+	want := append(append([]byte{}, payload...), bytes.Repeat([]byte(" "), wantPadding)...)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("reassembled payload does not match padded original")
+	}
+}
+
+func TestCompressionBypassFieldSkipsCompressionForMarkedMessages(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{CompressionBypassField: "_content_encoding"})
+
+	payload := strings.Repeat("a", 512)
+	if err := c.WriteMessage(&Message{
+		ShortMessage: payload,
+		Extra:        map[string]interface{}{"_content_encoding": "gzip"},
+	}); err != nil {
+		t.Fatalf("WriteMessage (bypassed): %v", err)
+	}
+	if err := c.WriteMessage(&Message{ShortMessage: payload}); err != nil {
+		t.Fatalf("WriteMessage (normal): %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 2 {
+		t.Fatalf("got %d packets, want 2", len(packets))
+	}
+	if isGzipped(packets[0]) {
+		t.Fatalf("bypassed message was compressed anyway")
+	}
+	if !isGzipped(packets[1]) {
+		t.Fatalf("non-bypassed message was not compressed")
+	}
+}
+
+func TestPadFinalChunkRejectedWithCompressionEnabled(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{PadFinalChunk: true})
+
+	err := c.WriteMessage(&Message{ShortMessage: "hi"})
+	if !errors.Is(err, errPadFinalChunkNeedsNoCompression) {
+		t.Fatalf("got %v, want errPadFinalChunkNeedsNoCompression", err)
+	}
+}
@@ -0,0 +1,41 @@
+package gelf
+
+import (
+	"sort"
+	"testing"
+)
+
+// chunkSequenceNumbers extracts the sequence byte from each of
+// packets, which must all be chunk packets belonging to one chunked
+// GELF message (see parseChunkPacket in reassemble.go).
+func chunkSequenceNumbers(t *testing.T, packets [][]byte) []int {
+	t.Helper()
+	seqs := make([]int, 0, len(packets))
+	for i, p := range packets {
+		entry, _, err := parseChunkPacket(p)
+		if err != nil {
+			t.Fatalf("packet %d: parseChunkPacket: %v", i, err)
+		}
+		seqs = append(seqs, entry.seq)
+	}
+	return seqs
+}
+
+// assertContiguousChunkSequence fails the test unless packets are
+// exactly one chunked message's packets, with sequence numbers
+// 0..len(packets)-1 and no gaps or duplicates. This is the precise
+// invariant a chunk-sending regression (skipping or double-sending a
+// sequence) would violate.
+func assertContiguousChunkSequence(t *testing.T, packets [][]byte) {
+	t.Helper()
+	if len(packets) == 0 {
+		t.Fatalf("assertContiguousChunkSequence: no packets")
+	}
+	seqs := chunkSequenceNumbers(t, packets)
+	sort.Ints(seqs)
+	for i, seq := range seqs {
+		if seq != i {
+			t.Fatalf("got sequence numbers %v, want exactly 0..%d with no gaps or duplicates", seqs, len(packets)-1)
+		}
+	}
+}
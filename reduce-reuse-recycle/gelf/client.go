@@ -0,0 +1,1117 @@
+package gelf
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Config controls how a Client behaves.
+type Config struct {
+	// SelfReportInterval, when non-zero, makes the Client periodically
+	// emit its own Stats as a GELF message so the health of the
+	// logging pipeline is visible in Graylog itself. Self-reports are
+	// excluded from the Stats they report.
+	SelfReportInterval time.Duration
+
+	// AddCaller fills in Message.File/Line via runtime.Caller when a
+	// message sent through WriteMessage doesn't already set them.
+	AddCaller bool
+
+	// OnChunked, when set, is called after a message that required
+	// more than one GELF chunk is sent, with the chunk-group ID and
+	// chunk count. This lets an application's own structured logger
+	// emit a correlating field (e.g. on the error log that triggered
+	// the oversized message) linking it to the chunks that crossed the
+	// wire, for cross-referencing with a packet capture.
+	OnChunked func(id [8]byte, count int)
+
+	// OnDrop, when set, is called for every message this client or one
+	// of its wrappers (AsyncClient, QuotaClient) drops instead of
+	// sending, with a DropReason identifying which of the several
+	// drop-capable features caused it. Every drop site already
+	// increments Stats.Dropped (or Stats.StaleDropped) on its own;
+	// OnDrop is for operators who want to count or inspect drops by
+	// reason rather than lump them into one counter, or log the
+	// specific message that didn't make it out. Called synchronously
+	// from the goroutine that decided to drop, so it must not block;
+	// it runs on every sampled-out debug message in a chatty
+	// configuration, not just rare failures.
+	OnDrop func(reason DropReason, m *Message)
+
+	// AnnotateChunking, when true, makes a multi-chunk message also
+	// send a companion debug-level GELF event carrying _chunk_count
+	// and _compressed_bytes fields, for diagnosing why large messages
+	// fail without permanently polluting every normal event with
+	// chunking details that are almost always irrelevant. The original
+	// event's own fields are untouched: its size is already fixed by
+	// the time the chunk count is known, too late to annotate it
+	// in place. Off by default. Single-chunk messages never get a
+	// companion event.
+	AnnotateChunking bool
+
+	// SampleRates, keyed by GELF level, thins high-volume logging
+	// (typically debug) before it's sent: a rate of 0.1 ships roughly
+	// one in ten messages at that level. A level with no entry is
+	// always sent. Sampled-out messages increment Stats.Dropped.
+	SampleRates map[int]float64
+
+	// Rand, when set, is used instead of the package-level math/rand
+	// source for sampling decisions, making SampleRates deterministic
+	// in tests.
+	Rand func() float64
+
+	// DedupSampleRates, keyed by GELF level like SampleRates, enables
+	// dedup-aware sampling: the first message at that level carrying a
+	// given ShortMessage (compared by hash) within DedupWindow is
+	// always sent, so a brand-new error is never the one that gets
+	// sampled away; later occurrences of the same ShortMessage within
+	// the window are sampled at the configured rate exactly like
+	// SampleRates, with a "_seen_count" extra field recording how many
+	// times it's been seen so far in the window. A level present in
+	// both DedupSampleRates and SampleRates uses DedupSampleRates.
+	// DedupSetSize bounds how many distinct ShortMessage hashes are
+	// tracked at once, oldest evicted first; zero uses a sensible
+	// default. DedupWindow zero disables windowing, so every message
+	// is treated as a first occurrence.
+	DedupSampleRates map[int]float64
+	DedupWindow      time.Duration
+	DedupSetSize     int
+
+	// Encoder, when set, replaces the default GELF JSON encoding of
+	// outgoing messages (e.g. with LogfmtEncoder). Compression,
+	// chunking, and transport are unaffected either way.
+	Encoder Encoder
+
+	// DialRetries and DialBackoff let NewClient retry its initial dial
+	// instead of failing outright when the destination isn't ready
+	// yet (common with orchestrated startup ordering). Stream-mode
+	// transports that dial eagerly (TCP, TLS, HTTP) reuse the same
+	// mechanism.
+	DialRetries int
+	DialBackoff time.Duration
+
+	// PadFinalChunk, when set, pads a multi-chunk message's final chunk
+	// with ASCII space bytes up to the configured chunk size, for
+	// receivers that mishandle a final chunk smaller than the rest --
+	// padding isn't required by the GELF spec, but trailing whitespace
+	// is silently ignored by a JSON decoder, so it's safe to add.
+	// That safety only holds for uncompressed payloads: padding a gzip
+	// stream corrupts it, so PadFinalChunk requires DisableCompression,
+	// and WriteMessage/WriteTo return an error if both are set.
+	PadFinalChunk bool
+
+	// WriteBufferBytes, when nonzero, sets the OS send buffer size on
+	// the socket NewClient or NewClientWithConn constructs, via
+	// SetWriteBuffer, before the Client is returned. A burst of
+	// multi-chunk messages can otherwise overflow the OS's default UDP
+	// send buffer and drop packets with ENOBUFS, so this lets callers
+	// raise it without reaching past the Client for the underlying
+	// socket themselves. If the conn doesn't support SetWriteBuffer
+	// (see writeBufferSetter), or the call fails, it's ignored
+	// silently.
+	WriteBufferBytes int
+
+	// Deterministic, InstanceID, and StartCounter together let a test
+	// or an incident replay reproduce the exact sequence of message IDs
+	// a Client generated, instead of the default scheme (the ID prefix
+	// salted with the wall-clock time newClient ran, and a counter that
+	// always starts at zero) which can never be reproduced after the
+	// fact. With Deterministic set, the ID prefix is derived from
+	// InstanceID alone (no time component), and the message counter
+	// starts at StartCounter instead of zero; two Clients with identical
+	// Deterministic, InstanceID, and StartCounter values produce
+	// identical ID sequences. This intentionally reintroduces the ID
+	// collisions the time-salted default exists to avoid -- never set
+	// Deterministic outside a test or a one-off replay of a specific
+	// past run.
+	Deterministic bool
+	InstanceID    string
+	StartCounter  uint32
+
+	// MessageIDFunc, when set, replaces the client's default
+	// prefix-plus-counter message-ID generation. Mainly useful for
+	// tests that need deterministic or colliding IDs.
+	MessageIDFunc func() [8]byte
+
+	// ErrorHandler, when set, receives errors the client can't return
+	// directly to a caller: today, a detected duplicate message ID.
+	ErrorHandler func(error)
+
+	// DetectDuplicateIDs tracks the last N generated message IDs and
+	// calls ErrorHandler with a *DuplicateMessageIDError if a new one
+	// matches. Off by default; diagnostic only, it doesn't block the
+	// send.
+	DetectDuplicateIDs bool
+
+	// DisableAutoPrefix stops the client from adding the GELF-required
+	// leading underscore to Extra keys; use this when the caller
+	// already manages prefixing itself. Keys that already start with
+	// "_" are never double-prefixed either way.
+	DisableAutoPrefix bool
+
+	// KeyTransform, when set, rewrites every Extra and Fields key
+	// before it's sent -- e.g. Go's CamelCase struct-derived names into
+	// the lowercase snake_case a Graylog dashboard expects. See
+	// SnakeCase for a ready-made transform. It runs before auto-prefixing
+	// (so it only ever sees the bare key, never a leading "_") and
+	// before the reserved-field check, so a transform that happens to
+	// produce "id" or "_id" is still caught. Only affects the default
+	// GELF JSON encoding; a custom Config.Encoder is responsible for its
+	// own key handling.
+	KeyTransform func(string) string
+
+	// MessageTTL, used by AsyncClient, bounds how long a queued message
+	// may wait before being dequeued: once it's older than MessageTTL
+	// it's dropped (incrementing Stats.StaleDropped) instead of sent.
+	// Zero means queued messages never go stale.
+	MessageTTL time.Duration
+
+	// PrecheckMessageSize, used by AsyncClient, makes WriteMessage
+	// validate a message's chunk count synchronously, before
+	// enqueueing it, instead of only discovering an oversized message
+	// later, inside the background sender, where the caller that wrote
+	// it has long since gotten back a success. A message that fails
+	// this check returns *ErrMessageTooLarge immediately instead of
+	// being enqueued. The check measures the message's raw, uncompressed
+	// length, which can only overestimate its eventual chunk count, so
+	// it never has false negatives but can have false positives --
+	// see PrecheckCompression for an exact check.
+	PrecheckMessageSize bool
+
+	// PrecheckCompression sharpens PrecheckMessageSize's estimate by
+	// compressing the message synchronously during the pre-check
+	// rather than just measuring its uncompressed length, matching
+	// exactly what the background sender will see. This costs an
+	// extra compression pass per message -- once here, once more when
+	// it's actually sent -- so it's opt-in on top of
+	// PrecheckMessageSize rather than its default behavior.
+	PrecheckCompression bool
+
+	// MaxRetries, RetryBackoff, RetryQueueSize, and Fallback, used by
+	// AsyncClient, govern retrying a message that fails with a
+	// temporary error (see isTemporaryError) instead of dropping it
+	// outright: it's held on a separate retry queue and re-attempted
+	// after RetryBackoff, doubling on each subsequent attempt, until
+	// either it's sent or MaxRetries attempts are exhausted -- at which
+	// point Fallback, if set, receives it instead. Zero MaxRetries (the
+	// default) disables retrying entirely, preserving today's
+	// drop-on-failure behavior. RetryQueueSize bounds the retry queue
+	// itself, separately from the main queue, so a prolonged outage
+	// can't grow it without limit; a message that fails while the
+	// retry queue is full is simply dropped (Stats.Dropped). Zero means
+	// no room for retries even though MaxRetries is set.
+	MaxRetries     int
+	RetryBackoff   time.Duration
+	RetryQueueSize int
+	Fallback       func(m *Message)
+
+	// QuotaBytes and QuotaWindow, used by QuotaClient, cap how many
+	// encoded bytes may be sent within a rolling window: once QuotaBytes
+	// have gone out, further messages are dropped (Stats.Dropped) until
+	// the window rolls over. This is a hard cap for cost control, not a
+	// pacing mechanism -- contrast with SampleRates, which thins volume
+	// proportionally rather than cutting it off. Zero QuotaBytes means
+	// QuotaClient enforces no cap.
+	QuotaBytes  int64
+	QuotaWindow time.Duration
+
+	// SenderConcurrency, used by AsyncClient, is how many goroutines
+	// concurrently pop from the queue and deliver messages, instead of
+	// the default single sender. Raising it lets a slow or high-volume
+	// destination drain the queue faster, at the cost of reordering:
+	// messages at the same level are no longer strictly FIFO once more
+	// than one goroutine can be mid-delivery at once. All senders share
+	// the underlying Client, including its c.compressors pool, so
+	// raising SenderConcurrency doesn't multiply the number of
+	// gzip.Writers held idle -- each sender only holds one out of the
+	// pool for the duration of a single message's compression. Values
+	// less than 2 behave exactly like the zero value: a single sender.
+	SenderConcurrency int
+
+	// Transport, when set, replaces the client's default UDP send
+	// path: compressed, chunked GELF packets are handed to it instead
+	// of being written directly to a UDP socket. Leave nil to use UDP.
+	Transport Transport
+
+	// WriteDeadlineBase and WriteDeadlinePerChunk together bound how
+	// long a single message's send may take, scaled to the work it
+	// actually requires: the deadline set on the connection before
+	// writing is WriteDeadlineBase + WriteDeadlinePerChunk*chunkCount,
+	// so a large multi-chunk message gets proportionally more time than
+	// a small single-packet one under the same configuration, rather
+	// than one fixed timeout either starving big messages or
+	// over-permitting small ones. WriteDeadlineBase zero (the default)
+	// leaves the connection's deadline alone entirely; only takes effect
+	// when the underlying conn supports SetWriteDeadline and no custom
+	// Transport is configured -- a custom Transport manages its own
+	// deadlines instead.
+	WriteDeadlineBase     time.Duration
+	WriteDeadlinePerChunk time.Duration
+
+	// EmitLifecycle, when true, sends a GELF event when the client
+	// starts (from NewClient/newClient) and another when it stops
+	// (from Close, before the connection is released), each tagged
+	// with the client's instance ID. Useful for auditing when a
+	// logging pipeline came up or went down.
+	EmitLifecycle bool
+
+	// CompressionLevel is a compress/gzip or compress/zlib level,
+	// depending on CompressionType -- the two packages share the same
+	// level constants. Zero (the Go zero value) means
+	// gzip.DefaultCompression; see ParseCompressionLevel for turning a
+	// config string into this value.
+	CompressionLevel int
+
+	// CompressionType selects gzip (the zero value) or zlib for
+	// whichever of the gzip-era fields above end up compressing a
+	// message -- CompressionLevel's interpretation, compressAllPooled,
+	// and StreamingCompression's streaming path all defer to it.
+	CompressionType CompressionType
+
+	// DisableCompression skips compression entirely and sends raw GELF
+	// JSON.
+	DisableCompression bool
+
+	// CompressionThreshold and CompressionMaxSize bound the payload
+	// sizes that get compressed: below CompressionThreshold, gzip
+	// overhead isn't worth it; above CompressionMaxSize, the payload
+	// is assumed already-dense (e.g. a base64 blob) and compressing it
+	// again is wasted CPU. Payloads outside [threshold, maxSize] are
+	// sent uncompressed but still chunked. Zero means "no bound" on
+	// that side of the window.
+	CompressionThreshold int
+	CompressionMaxSize   int
+
+	// AutoCompress enables the CompressionThreshold/CompressionMaxSize
+	// window without requiring the caller to pick the bounds: either
+	// one left at zero falls back to a built-in default
+	// (defaultAutoCompressThreshold, defaultAutoCompressMaxSize) instead
+	// of "no bound" on that side. Use CompressionThreshold/
+	// CompressionMaxSize directly when the defaults don't fit a given
+	// workload; AutoCompress is for callers who just want a reasonable
+	// windowed policy without tuning it.
+	AutoCompress bool
+
+	// MinCompressionBenefit, when non-zero, is the minimum fraction by
+	// which gzip must shrink a payload for the compressed form to be
+	// sent; below it, the original uncompressed bytes are sent instead.
+	// Guards against incompressible input (already-compressed blobs,
+	// random bytes) coming out of gzip larger than it went in. Not
+	// consulted in StreamingCompression mode, which commits to sending
+	// compressed output as it's produced.
+	MinCompressionBenefit float64
+
+	// CompressionBypassField, when set, names an Extra key a caller can
+	// set truthy on an individual Message (e.g. "_content_encoding") to
+	// skip this client's own compression for that message and send it
+	// as-is, still chunked if it doesn't fit a single datagram. Meant
+	// for payloads that arrive already compressed, where recompressing
+	// would be wasted CPU (and, for gzip, wrong -- the streams aren't
+	// concatenable); the server is expected to detect the payload on
+	// its own, same as WriteCompressed's callers rely on. Unlike
+	// CompressionThreshold/CompressionMaxSize, which bound compression
+	// by size for every message, this is an explicit per-message
+	// opt-out.
+	CompressionBypassField string
+
+	// StreamingCompression compresses incrementally instead of
+	// building the whole compressed payload in one buffer before
+	// chunking. See compressStreaming for the tradeoffs.
+	StreamingCompression bool
+
+	// MaxInFlightBytes, in StreamingCompression mode, bounds how much
+	// compressed output may sit produced-but-not-yet-collected for
+	// sending at once: once that much is buffered, the compression
+	// goroutine blocks until the collecting side catches up, rather
+	// than letting a tiny chunk size or an unusually large message grow
+	// memory use without limit. Zero (the default) collects with no
+	// bound, as before. See compressStreamingBounded.
+	MaxInFlightBytes int
+
+	// SchemaValidate, when set, is invoked on every message before it
+	// is sent. A non-nil error is returned to the caller and the
+	// message is never sent. See RequireFields for a ready-made
+	// validator.
+	SchemaValidate func(Message) error
+
+	// Clock overrides how the Client reads the current time and
+	// schedules tickers, for deterministic tests of timing-dependent
+	// behavior. Defaults to the real time package.
+	Clock Clock
+
+	// NormalizeNewlines converts "\r\n" to "\n" in ShortMessage and
+	// FullMessage before sending, so multi-line content captured from
+	// Windows tools renders cleanly in Graylog instead of leaving a
+	// stray "\r" at the end of every line.
+	NormalizeNewlines bool
+
+	// AddProcessInfo injects `_pid` (cached once per Client, from
+	// os.Getpid) and `_goroutines` (runtime.NumGoroutine(), read fresh
+	// per message) into every structured message's Extra. Off the hot
+	// path entirely when false.
+	AddProcessInfo bool
+
+	// StackOnError, when non-zero, captures the calling goroutine's
+	// stack via runtime.Stack for any WriteMessage call at or below
+	// this GELF Level (numerically; lower is more severe, so e.g. 3
+	// covers error and everything more severe than it), attaching it as
+	// FullMessage if that's unset, or otherwise as the `_stacktrace`
+	// extra field. Frames inside this package itself are stripped from
+	// the top, so the trace starts at the caller. StackBufferSize bounds
+	// the capture buffer (see captureStack); zero uses a sensible
+	// default.
+	StackOnError    int32
+	StackBufferSize int
+
+	// MaxFieldValueBytes, when non-zero, truncates ShortMessage,
+	// FullMessage, and any string-valued Extra entry that exceeds it,
+	// appending a "…[truncated N bytes]" marker naming how much was
+	// dropped. Guards against one oversized field (an accidentally
+	// dumped HTTP body, say) bloating an event or pushing it past the
+	// GELF chunk limit. Applied last, after StackOnError and any other
+	// field injection, so an injected stack trace is subject to the
+	// same limit as caller-supplied content. Off by default.
+	MaxFieldValueBytes int
+
+	// AddGoroutineID additionally injects `_goid`, the current
+	// goroutine's ID parsed out of a runtime.Stack trace. Gated
+	// separately from AddProcessInfo because parsing the stack is
+	// hacky (it relies on the undocumented "goroutine N [...]" line
+	// format) and meaningfully more expensive; best-effort, and left
+	// out of the field entirely if parsing fails.
+	AddGoroutineID bool
+
+	// AddUptime injects `_uptime_seconds`, the elapsed time since this
+	// Client was constructed (via the injectable Clock, not wall-clock
+	// time directly), as a float. Cheap -- one subtraction -- and
+	// useful for spotting anomalies clustered right after a restart
+	// without having to cross-reference deploy timestamps separately.
+	AddUptime bool
+
+	// MaxFields, when non-zero, bounds how many fields a message's
+	// Extra and Fields may carry between them (the fixed GELF fields --
+	// host, short_message, timestamp, level, and so on -- don't count).
+	// FieldOverflowStrategy governs what happens to a message over the
+	// limit; the zero value, FieldOverflowReject, fails it outright.
+	MaxFields             int
+	FieldOverflowStrategy FieldOverflowStrategy
+
+	// DebugSink, when set, receives a newline-delimited GELF JSON copy
+	// of every message passed to WriteMessage, before any sampling,
+	// dedup, or other dropping decision -- useful for watching full
+	// traffic locally while the primary transport sheds load under
+	// SampleRates or DedupSampleRates. Writes are best-effort: encode
+	// or write failures are silently ignored, since DebugSink must
+	// never be the reason a send fails. If DebugSink isn't itself safe
+	// for concurrent writes, wrap it with NewSerializingWriter.
+	DebugSink io.Writer
+
+	// Source, when set, overrides hostname detection and is used as the
+	// GELF host field for every message this Client sends. Unlike a
+	// fallback, it's a deliberate logical-identity override: use it
+	// when Graylog's source should be a stable service name rather
+	// than the machine hostname, e.g. in setups where many instances of
+	// the same service should report under one name.
+	Source string
+
+	// HostFunc, when set, is called once at construction to determine
+	// the GELF host field, instead of os.Hostname. Use it to source
+	// the host from an environment variable (e.g. NODE_NAME) or the
+	// Kubernetes downward API without paying a per-message lookup
+	// cost. Source, if also set, still takes precedence.
+	HostFunc func() string
+
+	// SignKey, when set, causes every message to carry an HMAC-SHA256
+	// over its own serialized content as a "_signature" extra field,
+	// for tamper-evidence in security-sensitive log pipelines. The
+	// receiving side verifies it with VerifyGELF.
+	SignKey []byte
+
+	// CoalesceWindow and CoalesceLevel, both set, combine a chatty
+	// component's consecutive same-level small messages into one GELF
+	// event: messages at CoalesceLevel are buffered, joined into
+	// FullMessage (one per line) with a "_coalesced_count" extra
+	// field, and flushed as a single send -- either when CoalesceWindow
+	// elapses or when a message at any other level arrives. Messages at
+	// other levels are unaffected and flush immediately as always.
+	CoalesceWindow time.Duration
+	CoalesceLevel  int
+
+	// TransformMessage, when set, is called on the structured path just
+	// before encoding -- after SchemaValidate and after the host and
+	// timestamp defaults are filled in, so it sees exactly what's about
+	// to be serialized. It may mutate m in place, e.g. to redact a
+	// credit-card or token pattern out of ShortMessage/FullMessage/
+	// Extra before the message leaves the process.
+	//
+	// Transform, when set, is called on the raw path: the fully encoded
+	// bytes (Encoder's output, including a SignKey signature if one is
+	// configured), after encoding and before compression and chunking.
+	// It may return a different, possibly differently-sized, slice.
+	// Because it runs after signing, using Transform to mutate a signed
+	// payload invalidates the signature; redact via TransformMessage
+	// instead when SignKey is also set.
+	//
+	// Both run after SchemaValidate, so validation sees the pre-transform
+	// content that was actually passed to WriteMessage.
+	TransformMessage func(m *Message)
+	Transform        func(b []byte) []byte
+
+	// Middleware composes a chain of Middleware around the terminal
+	// handler that encodes and sends a message, an alternative to
+	// TransformMessage for behavior that's cleaner expressed as an
+	// independent, composable unit (redaction, field injection, a
+	// checksum) than as another Config flag. See Middleware and
+	// MessageHandler. Applied after WriteMessage's own preprocessing
+	// (AddCaller, NormalizeNewlines, and the rest), same as
+	// TransformMessage.
+	Middleware []Middleware
+
+	// CrashDumpSize, when nonzero, retains the last CrashDumpSize
+	// structured Messages this Client sent in an in-memory ring buffer,
+	// for post-mortem debugging: see Client.DumpRecent. Captured after
+	// TransformMessage runs, so the retained copy reflects exactly what
+	// was encoded, redactions included. Installing a crash handler
+	// (signal.Notify, or a deferred recover) that calls DumpRecent is
+	// left to the caller; this only maintains the buffer. Zero (the
+	// default) keeps no history and costs nothing.
+	CrashDumpSize int
+
+	// MaxPooled, when nonzero, caps how many compressors (each a
+	// gzip.Writer plus its buffer) compressAllPooled keeps retained for
+	// reuse, trading sync.Pool's default GC-cooperative behavior for a
+	// predictable memory ceiling: see boundedCompressorPool. Zero (the
+	// default) uses an ordinary sync.Pool, which is unbounded but lets
+	// the GC reclaim retained compressors under memory pressure --
+	// usually the better tradeoff, unless an adversarial burst of
+	// concurrent sends needs a hard cap instead.
+	MaxPooled int
+}
+
+// packetConn is the subset of net.PacketConn a Client needs. It exists
+// so tests can substitute a fake connection without opening a real
+// socket.
+type packetConn interface {
+	WriteTo(b []byte, addr net.Addr) (int, error)
+	Close() error
+}
+
+// Client sends Messages to a single GELF UDP destination, chunking and
+// compressing as needed.
+type Client struct {
+	conn packetConn
+	addr atomic.Pointer[net.Addr]
+	cfg  Config
+	host string
+
+	messageCount uint32
+	idPrefix     [4]byte
+	chunkSize    atomic.Int64
+	instanceID   string
+	recentIDs    *recentIDSet
+	lastChunks   atomic.Int64
+	pid          int
+	compressors  compressorPool
+	crashRing    *crashRing
+	dedupSeen    *dedupSampler
+	handler      MessageHandler
+
+	coalesceMu sync.Mutex
+	coalesce   *coalesceBatch
+
+	startTime time.Time
+
+	// streamMode is set by NewTCPClient (and any future stream-based
+	// constructor): it disables UDP-only behavior -- chunking above
+	// maxChunkSize and gzip compression -- that makes no sense once
+	// Config.Transport frames whole messages over a persistent
+	// connection instead of independent datagrams.
+	streamMode bool
+
+	Stats Stats
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewClient dials addr over UDP and returns a Client that sends to it.
+func NewClient(addr string, cfg Config) (*Client, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var conn *net.UDPConn
+	err = dialWithRetry(cfg, func() error {
+		c, err := net.ListenUDP("udp", nil)
+		if err != nil {
+			return err
+		}
+		conn = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	applyWriteBufferBytes(conn, cfg)
+	return newClient(conn, udpAddr, cfg), nil
+}
+
+// writeBufferSetter is the subset of *net.UDPConn Config.WriteBufferBytes
+// needs. Checked with a type assertion, like deadlineSetter, rather
+// than folded into packetConn, since most test fakes have no use for
+// it and it lets a test substitute a fake recording the call instead
+// of asserting on a real socket's buffer size.
+type writeBufferSetter interface {
+	SetWriteBuffer(bytes int) error
+}
+
+// applyWriteBufferBytes applies Config.WriteBufferBytes to conn, if
+// set and conn supports SetWriteBuffer, ignoring any error -- a send
+// buffer that couldn't be resized leaves the client working at the OS
+// default, not broken.
+func applyWriteBufferBytes(conn net.PacketConn, cfg Config) {
+	if cfg.WriteBufferBytes <= 0 {
+		return
+	}
+	if wbs, ok := conn.(writeBufferSetter); ok {
+		_ = wbs.SetWriteBuffer(cfg.WriteBufferBytes)
+	}
+}
+
+// NewClientWithConn returns a Client that sends to addr over an
+// already-constructed net.PacketConn, for callers that need control
+// over socket setup NewClient doesn't expose -- e.g. a specific
+// source port via DialUDP, or a conn bound to a particular network
+// interface. If conn is a *SharedConn, this acquires a reference on
+// it, so the returned Client's Close won't close the underlying
+// socket out from under any other Client sharing it.
+func NewClientWithConn(conn net.PacketConn, addr net.Addr, cfg Config) *Client {
+	if sc, ok := conn.(*SharedConn); ok {
+		sc.Acquire()
+	}
+	applyWriteBufferBytes(conn, cfg)
+	return newClient(conn, addr, cfg)
+}
+
+// dialWithRetry calls dial, retrying up to cfg.DialRetries times with
+// cfg.DialBackoff between attempts, should the initial dial target
+// not be ready yet. Stream-mode transports (TCP, TLS, HTTP) added
+// later dial eagerly and will reuse this same mechanism; UDP rarely
+// needs it, but NewClient wires it through for consistency.
+func dialWithRetry(cfg Config, dial func() error) error {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = dial(); err == nil {
+			return nil
+		}
+		if attempt >= cfg.DialRetries {
+			return err
+		}
+		if cfg.DialBackoff > 0 {
+			clk := cfg.Clock
+			if clk == nil {
+				clk = realClock{}
+			}
+			ticker := clk.NewTicker(cfg.DialBackoff)
+			<-ticker.C()
+			ticker.Stop()
+		}
+	}
+}
+
+// newClient builds a Client around an already-connected packetConn.
+// It is the shared constructor used by NewClient and by tests that
+// substitute a fake connection.
+func newClient(conn packetConn, addr net.Addr, cfg Config) *Client {
+	host := cfg.Source
+	if host == "" {
+		hostFunc := cfg.HostFunc
+		if hostFunc == nil {
+			hostFunc = func() string {
+				h, err := os.Hostname()
+				if err != nil {
+					return "unknown"
+				}
+				return h
+			}
+		}
+		host = hostFunc()
+	}
+
+	c := &Client{
+		conn: conn,
+		cfg:  cfg,
+		host: host,
+		pid:  os.Getpid(),
+		done: make(chan struct{}),
+	}
+	c.addr.Store(&addr)
+	c.startTime = c.clock().Now()
+	if cfg.Deterministic {
+		copy(c.idPrefix[:], []byte(cfg.InstanceID))
+		c.messageCount = cfg.StartCounter
+	} else {
+		copy(c.idPrefix[:], []byte(host+time.Now().String()))
+	}
+	c.chunkSize.Store(defaultMaxChunkSize)
+	c.instanceID = fmt.Sprintf("%x", c.idPrefix)
+	if cfg.DetectDuplicateIDs {
+		c.recentIDs = newRecentIDSet(recentIDSetSize)
+	}
+	if cfg.CrashDumpSize > 0 {
+		c.crashRing = newCrashRing(cfg.CrashDumpSize)
+	}
+	if len(cfg.DedupSampleRates) > 0 {
+		c.dedupSeen = newDedupSampler(cfg.DedupSetSize, cfg.DedupWindow, c.clock())
+	}
+	if cfg.MaxPooled > 0 {
+		c.compressors = newBoundedCompressorPool(cfg.MaxPooled)
+	} else {
+		c.compressors = &unboundedCompressorPool{}
+	}
+	c.handler = c.buildHandler()
+
+	if cfg.EmitLifecycle {
+		c.writeMessage(context.Background(), &Message{
+			ShortMessage: "gelf client started",
+			Level:        6, // informational
+			Extra:        c.lifecycleFields(),
+		}, &c.Stats)
+	}
+
+	if cfg.SelfReportInterval > 0 {
+		// The ticker is created here, synchronously, rather than inside
+		// the goroutine: callers that inject a fake Clock need it
+		// registered before they can advance time and expect a tick.
+		ticker := c.clock().NewTicker(cfg.SelfReportInterval)
+		c.wg.Add(1)
+		go c.selfReportLoop(ticker)
+	}
+
+	return c
+}
+
+// lifecycleFields summarizes the client's configuration for a
+// lifecycle event's Extra fields.
+func (c *Client) lifecycleFields() map[string]interface{} {
+	return map[string]interface{}{
+		"instance_id":         c.instanceID,
+		"compression_enabled": !c.cfg.DisableCompression,
+		"self_report_enabled": c.cfg.SelfReportInterval > 0,
+	}
+}
+
+// rand returns a float64 in [0, 1) using Config.Rand if set, or the
+// package-level math/rand source otherwise.
+func (c *Client) rand() float64 {
+	if c.cfg.Rand != nil {
+		return c.cfg.Rand()
+	}
+	return rand.Float64()
+}
+
+// messageID returns the 8-byte chunk-group ID for the next message: a
+// per-client fixed prefix followed by a monotonically increasing
+// counter. It does not allocate.
+func (c *Client) messageID() [8]byte {
+	id := c.generateMessageID()
+	if c.recentIDs != nil && c.recentIDs.seenAndAdd(id) {
+		if c.cfg.ErrorHandler != nil {
+			c.cfg.ErrorHandler(&DuplicateMessageIDError{ID: id})
+		}
+	}
+	return id
+}
+
+// generateMessageID produces the raw ID, deferring to
+// Config.MessageIDFunc if one is set.
+func (c *Client) generateMessageID() [8]byte {
+	if c.cfg.MessageIDFunc != nil {
+		return c.cfg.MessageIDFunc()
+	}
+	var id [8]byte
+	copy(id[0:4], c.idPrefix[:])
+	n := atomic.AddUint32(&c.messageCount, 1)
+	binary.LittleEndian.PutUint32(id[4:8], n)
+	return id
+}
+
+// writeDebugSink encodes a snapshot of m as GELF JSON and writes it,
+// newline-delimited, to Config.DebugSink, independent of whatever
+// sampling, dedup, or other dropping decision writeMessage goes on to
+// make -- the whole point of DebugSink is a complete local record even
+// when the primary path is shedding load. It works from a shallow
+// copy so filling in a default Host/Timestamp for the encode doesn't
+// affect the real m passed on to the rest of writeMessage. Best-effort:
+// an encode or write failure is silently ignored rather than failing
+// the send DebugSink is only supposed to observe.
+func (c *Client) writeDebugSink(m *Message) {
+	snapshot := *m
+	if snapshot.Host == "" {
+		snapshot.Host = c.host
+	}
+	if snapshot.Timestamp.IsZero() {
+		snapshot.Timestamp = c.clock().Now()
+	}
+	b, err := c.encoder().Encode(&snapshot)
+	if err != nil {
+		return
+	}
+	_, _ = c.cfg.DebugSink.Write(append(b, '\n'))
+}
+
+// isTruthy reports whether an Extra value counts as "set" for
+// Config.CompressionBypassField: present and not the zero value for
+// its type. Mirrors the loose truthiness JSON-derived data tends to
+// carry (a bool, a non-empty string, a nonzero number) rather than
+// requiring callers to set exactly `true`.
+func isTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != "" && t != "false" && t != "0"
+	case float64:
+		return t != 0
+	case int:
+		return t != 0
+	default:
+		return true
+	}
+}
+
+// prepareMessage runs every WriteMessage/WriteContext preprocessing
+// step shared by both -- AddCaller, NormalizeNewlines, stack capture,
+// process-info/uptime injection, field-count/value-size enforcement,
+// and coalescing -- so the two entry points can never drift apart by
+// one of them gaining a fix or a new feature flag the other misses.
+// callerSkip is the number of stack frames between runtime.Caller and
+// the original WriteMessage/WriteContext call site. It's the same for
+// both callers: each calls prepareMessage directly, one frame away, so
+// both pass prepareMessageCallerSkip. done reports whether m was fully
+// handled here (coalesced, or rejected by a precheck) and the caller
+// should return err without going on to send.
+func (c *Client) prepareMessage(m *Message, callerSkip int) (done bool, err error) {
+	if c.cfg.AddCaller && m.File == "" && m.Line == 0 {
+		if _, file, line, ok := runtime.Caller(callerSkip); ok {
+			m.File = file
+			m.Line = line
+		}
+	}
+	if c.cfg.NormalizeNewlines {
+		m.ShortMessage = normalizeNewlines(m.ShortMessage)
+		m.FullMessage = normalizeNewlines(m.FullMessage)
+	}
+	m.ensureExtra()
+	if c.cfg.StackOnError != 0 && m.Level <= c.cfg.StackOnError {
+		stack := captureStack(c.cfg.StackBufferSize)
+		if m.FullMessage == "" {
+			m.FullMessage = stack
+		} else {
+			m.Extra["_stacktrace"] = stack
+		}
+	}
+	if c.cfg.AddProcessInfo {
+		m.Extra["_pid"] = c.pid
+		m.Extra["_goroutines"] = runtime.NumGoroutine()
+		if c.cfg.AddGoroutineID {
+			if id, ok := currentGoroutineID(); ok {
+				m.Extra["_goid"] = id
+			}
+		}
+	}
+	if c.cfg.AddUptime {
+		m.Extra["_uptime_seconds"] = c.clock().Now().Sub(c.startTime).Seconds()
+	}
+
+	if err := c.enforceMaxFields(m); err != nil {
+		c.Stats.Errors.Add(1)
+		return true, err
+	}
+
+	if max := c.cfg.MaxFieldValueBytes; max > 0 {
+		m.ShortMessage = truncateFieldValue(m.ShortMessage, max)
+		m.FullMessage = truncateFieldValue(m.FullMessage, max)
+		for k, v := range m.Extra {
+			if s, ok := v.(string); ok {
+				m.Extra[k] = truncateFieldValue(s, max)
+			}
+		}
+	}
+
+	if c.tryCoalesce(m) {
+		return true, nil
+	}
+	return false, nil
+}
+
+// prepareMessageCallerSkip is the runtime.Caller skip count that lands
+// on WriteMessage/WriteContext's own caller, from inside
+// prepareMessage: one frame for prepareMessage itself, one for the
+// WriteMessage/WriteContext frame that called it.
+const prepareMessageCallerSkip = 2
+
+// WriteMessage encodes and sends m, filling in Host and Timestamp if
+// they are unset, and File/Line if Config.AddCaller is set and the
+// caller hasn't already supplied them.
+func (c *Client) WriteMessage(m *Message) error {
+	if done, err := c.prepareMessage(m, prepareMessageCallerSkip); done {
+		return err
+	}
+	return c.handler(context.Background(), m)
+}
+
+// WriteContext is WriteMessage, additionally honoring ctx: it's
+// checked before the send begins, and again between chunks of a
+// multi-chunk message, so a canceled ctx stops a large send partway
+// through instead of running it to completion regardless. A message
+// short enough to fit in one datagram completes or fails atomically
+// either way, since there's only ever one chunk to check between.
+//
+// Coalesced messages (Config.CoalesceWindow) and the debug-sink tap
+// (Config.DebugSink) don't carry ctx forward to their own eventual
+// send, since that send happens on the coalesce timer or isn't a send
+// at all; ctx only bounds this call's own synchronous work.
+func (c *Client) WriteContext(ctx context.Context, m *Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if done, err := c.prepareMessage(m, prepareMessageCallerSkip); done {
+		return err
+	}
+	return c.handler(ctx, m)
+}
+
+// writeMessage does the actual encode-and-send, recording the outcome
+// in stats. Self-reports pass a throwaway Stats so they don't inflate
+// the counters they're reporting.
+func (c *Client) writeMessage(ctx context.Context, m *Message, stats *Stats) error {
+	if c.cfg.DebugSink != nil {
+		c.writeDebugSink(m)
+	}
+
+	if rate, ok := c.cfg.DedupSampleRates[int(m.Level)]; ok {
+		first, seenCount := c.dedupSeen.observe(m.ShortMessage)
+		if !first {
+			if rate < 1 && c.rand() >= rate {
+				stats.Dropped.Add(1)
+				c.cfg.notifyDrop(DropReasonDedupSampled, m)
+				return nil
+			}
+			m.ensureExtra()
+			m.Extra["_seen_count"] = seenCount
+		}
+	} else if rate, ok := c.cfg.SampleRates[int(m.Level)]; ok && rate < 1 && c.rand() >= rate {
+		stats.Dropped.Add(1)
+		c.cfg.notifyDrop(DropReasonSampled, m)
+		return nil
+	}
+
+	if c.cfg.SchemaValidate != nil {
+		if err := c.cfg.SchemaValidate(*m); err != nil {
+			stats.Errors.Add(1)
+			return err
+		}
+	}
+
+	if m.Host == "" {
+		m.Host = c.host
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = c.clock().Now()
+	}
+
+	if c.cfg.TransformMessage != nil {
+		c.cfg.TransformMessage(m)
+	}
+
+	if err := m.checkRequiredFields(); err != nil {
+		stats.Errors.Add(1)
+		return err
+	}
+
+	if c.crashRing != nil {
+		c.crashRing.add(*m)
+	}
+
+	b, err := c.encoder().Encode(m)
+	if err != nil {
+		stats.Errors.Add(1)
+		return err
+	}
+
+	if c.cfg.Transform != nil {
+		b = c.cfg.Transform(b)
+	}
+
+	// Snapshotting the destination once here, rather than re-reading
+	// c.addr at each use below, matters for a multi-chunk message: a
+	// concurrent SetServerAddr between two chunk sends would otherwise
+	// be able to split one message's chunks across two destinations,
+	// which the receiving end can never reassemble.
+	addr := c.destAddr()
+	skipCompression := c.cfg.CompressionBypassField != "" && isTruthy(m.Extra[c.cfg.CompressionBypassField])
+	if err := c.dispatchToSkipping(ctx, addr, b, stats, skipCompression); err != nil {
+		stats.Errors.Add(1)
+		return err
+	}
+
+	stats.Sent.Add(1)
+	return nil
+}
+
+// destAddr returns the client's current destination, snapshotted
+// atomically so a caller that reads it once at the start of a write
+// sees a single, consistent net.Addr for that write's whole duration,
+// even if SetServerAddr runs concurrently.
+func (c *Client) destAddr() net.Addr {
+	return *c.addr.Load()
+}
+
+// SetServerAddr changes the destination future writes are sent to.
+// Each write snapshots the destination once at its own start (see
+// destAddr), so a write already in progress always finishes against
+// the address it started with; only writes that start after this
+// returns see the new one. Safe to call concurrently with writes.
+func (c *Client) SetServerAddr(addr net.Addr) {
+	c.addr.Store(&addr)
+}
+
+// WriteTo sends p to addr instead of the client's default destination,
+// reusing the client's compression settings and message-ID counter.
+// addr must be a *net.UDPAddr, matching this client's connection type.
+func (c *Client) WriteTo(addr net.Addr, p []byte) (int, error) {
+	if _, ok := addr.(*net.UDPAddr); !ok {
+		return 0, fmt.Errorf("gelf: %T is not compatible with this client's UDP connection", addr)
+	}
+	if err := c.dispatchTo(context.Background(), addr, p, &c.Stats); err != nil {
+		c.Stats.Errors.Add(1)
+		return 0, err
+	}
+	c.Stats.Sent.Add(1)
+	return len(p), nil
+}
+
+// WriteCompressed sends compressed straight through the chunking path,
+// assigning it a message ID, without running it through this client's
+// own gzip writer. It's meant for a forwarding proxy that receives
+// already-compressed GELF from upstream, where re-gzipping would be
+// wasted CPU (and, since gzip streams aren't generally concatenable,
+// wrong). The caller is responsible for compressed being a validly
+// compressed GELF payload; WriteCompressed does not inspect it. The
+// GELF chunk-count limit still applies to the compressed length.
+// Config.PadFinalChunk is not checked here, since compressed is
+// compressed by definition; setting it together with WriteCompressed
+// will corrupt the final chunk, so don't.
+func (c *Client) WriteCompressed(compressed []byte) (int, error) {
+	if err := c.send(context.Background(), c.destAddr(), compressed); err != nil {
+		c.Stats.Errors.Add(1)
+		return 0, err
+	}
+	c.Stats.Sent.Add(1)
+	return len(compressed), nil
+}
+
+// Describe returns the effective, resolved settings this Client is
+// actually sending with -- after defaults, not the raw Config -- for
+// dumping into a support ticket when a client misbehaves in
+// production without access to the Config it was built with. Values
+// that would expose a secret (Config.SignKey) are reported only as
+// present/absent, never their contents.
+func (c *Client) Describe() map[string]string {
+	d := map[string]string{
+		"destination":           c.destAddr().String(),
+		"source":                c.host,
+		"compression_disabled":  strconv.FormatBool(c.cfg.DisableCompression),
+		"compression_level":     strconv.Itoa(c.compressionLevel()),
+		"compression_streaming": strconv.FormatBool(c.cfg.StreamingCompression),
+		"max_chunk_size":        strconv.Itoa(c.MaxChunkSize()),
+		"transport":             "udp",
+		"signed":                strconv.FormatBool(len(c.cfg.SignKey) > 0),
+		"deterministic_ids":     strconv.FormatBool(c.cfg.Deterministic),
+		"sender_concurrency":    strconv.Itoa(c.cfg.SenderConcurrency),
+	}
+	if c.cfg.Transport != nil {
+		d["transport"] = fmt.Sprintf("%T", c.cfg.Transport)
+	}
+	if c.cfg.SelfReportInterval > 0 {
+		d["self_report_interval"] = c.cfg.SelfReportInterval.String()
+	}
+	if len(c.cfg.SampleRates) > 0 {
+		d["sampling"] = "enabled"
+	}
+	if len(c.cfg.DedupSampleRates) > 0 {
+		d["dedup_sampling"] = "enabled"
+	}
+	return d
+}
+
+func (c *Client) selfReportLoop(ticker Ticker) {
+	defer c.wg.Done()
+	defer ticker.Stop()
+
+	var discard Stats
+	for {
+		select {
+		case <-ticker.C():
+			c.writeMessage(context.Background(), &Message{
+				ShortMessage: "gelf client self-report",
+				Level:        6, // informational
+				Extra:        c.Stats.snapshot(),
+			}, &discard)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// DrainStats atomically swaps Sent, Dropped, Errors, and StaleDropped
+// to zero and returns their pre-swap values, so a periodic health
+// reporter can read a clean window's counts without racing a
+// separate read-then-reset (which could lose increments landing
+// between the two).
+func (c *Client) DrainStats() StatsSnapshot {
+	return StatsSnapshot{
+		Sent:              c.Stats.Sent.Swap(0),
+		Dropped:           c.Stats.Dropped.Swap(0),
+		Errors:            c.Stats.Errors.Swap(0),
+		StaleDropped:      c.Stats.StaleDropped.Swap(0),
+		UncompressedBytes: c.Stats.UncompressedBytes.Swap(0),
+		BytesSent:         c.Stats.BytesSent.Swap(0),
+	}
+}
+
+// Close stops the self-report loop, if running, and closes the
+// underlying connection.
+func (c *Client) Close() error {
+	close(c.done)
+	c.wg.Wait()
+
+	if c.cfg.EmitLifecycle {
+		c.writeMessage(context.Background(), &Message{
+			ShortMessage: "gelf client stopped",
+			Level:        6, // informational
+			Extra:        c.lifecycleFields(),
+		}, &c.Stats)
+	}
+
+	if c.cfg.Transport != nil {
+		return c.cfg.Transport.Close()
+	}
+	return c.conn.Close()
+}
+
+var _ io.Closer = (*Client)(nil)
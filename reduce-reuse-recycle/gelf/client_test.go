@@ -0,0 +1,402 @@
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForPackets polls conn until at least n packets have been
+// recorded or a short timeout elapses. The fake clock makes the
+// *ticking* deterministic; the self-report goroutine still needs a
+// moment to actually receive and act on the tick.
+func waitForPackets(t *testing.T, conn *memConn, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if len(conn.Packets()) >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d packet(s), got %d", n, len(conn.Packets()))
+}
+
+func packetsAsString(conn *memConn) string {
+	var sb strings.Builder
+	for _, p := range conn.Packets() {
+		sb.Write(p)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func TestClientSelfReport(t *testing.T) {
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		SelfReportInterval: time.Minute,
+		DisableCompression: true,
+		Clock:              clk,
+	})
+	defer c.Close()
+
+	clk.Advance(time.Minute)
+	waitForPackets(t, conn, 1)
+
+	var found map[string]interface{}
+	for _, p := range conn.Packets() {
+		var m map[string]interface{}
+		if err := json.Unmarshal(p, &m); err == nil {
+			found = m
+			break
+		}
+	}
+	if found == nil {
+		t.Fatalf("expected at least one decodable self-report packet, got %q", packetsAsString(conn))
+	}
+	if _, ok := found["_sent"]; !ok {
+		t.Fatalf("expected a _sent extra field, got %v", found)
+	}
+
+	if c.Stats.Sent.Load() != 0 {
+		t.Fatalf("self-report should not inflate its own Stats, got Sent=%d", c.Stats.Sent.Load())
+	}
+}
+
+func TestWriteMessageNilExtraDoesNotPanic(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		AddCaller:          true,
+		DisableCompression: true,
+	})
+
+	m := &Message{ShortMessage: "x"}
+	if err := c.WriteMessage(m); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &decoded); err != nil {
+		t.Fatalf("decode sent packet: %v", err)
+	}
+	if decoded["short_message"] != "x" {
+		t.Fatalf("got %v", decoded)
+	}
+}
+
+func TestClientEmitLifecycleSendsStartAndStopEvents(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		EmitLifecycle:      true,
+		DisableCompression: true,
+	})
+
+	if len(conn.Packets()) != 1 {
+		t.Fatalf("expected 1 packet after start, got %d", len(conn.Packets()))
+	}
+	var started map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &started); err != nil {
+		t.Fatalf("decode start event: %v", err)
+	}
+	if started["short_message"] != "gelf client started" {
+		t.Fatalf("got %v", started)
+	}
+	if _, ok := started["_instance_id"]; !ok {
+		t.Fatalf("expected an _instance_id extra field, got %v", started)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(conn.Packets()) != 2 {
+		t.Fatalf("expected 2 packets after close, got %d", len(conn.Packets()))
+	}
+	var stopped map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[1], &stopped); err != nil {
+		t.Fatalf("decode stop event: %v", err)
+	}
+	if stopped["short_message"] != "gelf client stopped" {
+		t.Fatalf("got %v", stopped)
+	}
+}
+
+func TestWriteMessageAutoPrefixIsIdempotentAndDisableable(t *testing.T) {
+	tests := []struct {
+		name              string
+		disableAutoPrefix bool
+		key               string
+		wantKey           string
+	}{
+		{"unprefixed key gets prefixed", false, "foo", "_foo"},
+		{"already-prefixed key is untouched", false, "_foo", "_foo"},
+		{"disabled leaves unprefixed key as-is", true, "foo", "foo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &memConn{}
+			c := newClient(conn, testAddr, Config{
+				DisableCompression: true,
+				DisableAutoPrefix:  tt.disableAutoPrefix,
+			})
+			m := &Message{ShortMessage: "x", Extra: map[string]interface{}{tt.key: "v"}}
+			if err := c.WriteMessage(m); err != nil {
+				t.Fatalf("WriteMessage: %v", err)
+			}
+
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(conn.Packets()[0], &decoded); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if _, ok := decoded[tt.wantKey]; !ok {
+				t.Fatalf("expected key %q in %v", tt.wantKey, decoded)
+			}
+		})
+	}
+}
+
+func TestClientSourceOverridesHostname(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, Source: "checkout-service"})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1", len(packets))
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(packets[0], &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := fields["host"]; got != "checkout-service" {
+		t.Fatalf("got host %v, want %q", got, "checkout-service")
+	}
+}
+
+func TestHostFuncIsEvaluatedOnceAndCached(t *testing.T) {
+	conn := &memConn{}
+	calls := 0
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		HostFunc: func() string {
+			calls++
+			return "node-from-downward-api"
+		},
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("got %d HostFunc calls, want exactly 1 (cached at construction)", calls)
+	}
+
+	packets := conn.Packets()
+	var fields map[string]interface{}
+	if err := json.Unmarshal(packets[len(packets)-1], &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := fields["host"]; got != "node-from-downward-api" {
+		t.Fatalf("got host %v, want %q", got, "node-from-downward-api")
+	}
+}
+
+func TestTransformMessageRedactsBeforeTheSecretReachesTheWire(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		TransformMessage: func(m *Message) {
+			m.ShortMessage = strings.ReplaceAll(m.ShortMessage, "4111-1111-1111-1111", "[redacted]")
+		},
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "card 4111-1111-1111-1111 declined"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packet := conn.Packets()[0]
+	if strings.Contains(string(packet), "4111-1111-1111-1111") {
+		t.Fatalf("secret reached the capture conn: %s", packet)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(packet, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got, want := fields["short_message"], "card [redacted] declined"; got != want {
+		t.Fatalf("got short_message=%v, want %q", got, want)
+	}
+}
+
+func TestTransformRewritesTheEncodedBytesBeforeCompression(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Transform: func(b []byte) []byte {
+			return []byte(strings.ReplaceAll(string(b), "secret-token-xyz", "[redacted]"))
+		},
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "secret-token-xyz"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packet := conn.Packets()[0]
+	if strings.Contains(string(packet), "secret-token-xyz") {
+		t.Fatalf("secret reached the capture conn: %s", packet)
+	}
+}
+
+func TestWriteCompressedSendsPayloadUnmodifiedAndChunked(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{})
+
+	compressed := make([]byte, defaultMaxChunkSize*2+37)
+	for i := range compressed {
+		compressed[i] = byte(i * 7)
+	}
+
+	n, err := c.WriteCompressed(compressed)
+	if err != nil {
+		t.Fatalf("WriteCompressed: %v", err)
+	}
+	if n != len(compressed) {
+		t.Fatalf("got n=%d, want %d", n, len(compressed))
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 3 {
+		t.Fatalf("got %d packets, want 3", len(packets))
+	}
+
+	got, err := ReassembleChunks(packets)
+	if err != nil {
+		t.Fatalf("ReassembleChunks: %v", err)
+	}
+	if !bytes.Equal(got, compressed) {
+		t.Fatalf("reassembled bytes don't match the original compressed payload")
+	}
+}
+
+func TestAddProcessInfoIncludesPID(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		AddProcessInfo:     true,
+		AddGoroutineID:     true,
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "x"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got, want := fields["_pid"], float64(os.Getpid()); got != want {
+		t.Fatalf("got _pid=%v, want %v", got, want)
+	}
+	if _, ok := fields["_goroutines"]; !ok {
+		t.Fatalf("expected a _goroutines field, got %v", fields)
+	}
+	if _, ok := fields["_goid"]; !ok {
+		t.Fatalf("expected a _goid field, got %v", fields)
+	}
+}
+
+func TestMaxFieldValueBytesTruncatesOversizedFields(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		MaxFieldValueBytes: 10,
+	})
+
+	if err := c.WriteMessage(&Message{
+		ShortMessage: "0123456789exceeds",
+		FullMessage:  "short",
+		Extra:        map[string]interface{}{"body": "0123456789exceeds", "count": 3},
+	}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	short, _ := fields["short_message"].(string)
+	if want := "0123456789…[truncated 7 bytes]"; short != want {
+		t.Fatalf("got short_message=%q, want %q", short, want)
+	}
+	if got, want := fields["full_message"], "short"; got != want {
+		t.Fatalf("got full_message=%v, want %q unchanged (fits within the limit)", got, want)
+	}
+	body, _ := fields["_body"].(string)
+	if want := "0123456789…[truncated 7 bytes]"; body != want {
+		t.Fatalf("got _body=%q, want %q", body, want)
+	}
+	if got, want := fields["_count"], float64(3); got != want {
+		t.Fatalf("got _count=%v, want %v unchanged (not a string)", got, want)
+	}
+}
+
+func TestWriteMessageRejectsReservedIDField(t *testing.T) {
+	for _, key := range []string{"id", "_id"} {
+		t.Run(key, func(t *testing.T) {
+			conn := &memConn{}
+			c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+			err := c.WriteMessage(&Message{ShortMessage: "x", Extra: map[string]interface{}{key: "123"}})
+			if err == nil {
+				t.Fatalf("expected an error for Extra[%q]", key)
+			}
+			var reserved *ErrReservedField
+			if !errors.As(err, &reserved) {
+				t.Fatalf("got %v, want *ErrReservedField", err)
+			}
+			if reserved.Key != key {
+				t.Fatalf("got Key=%q, want %q", reserved.Key, key)
+			}
+			if len(conn.Packets()) != 0 {
+				t.Fatalf("expected nothing to be sent, got %d packets", len(conn.Packets()))
+			}
+		})
+	}
+}
+
+func TestNormalizeNewlinesConvertsCRLFToLF(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, NormalizeNewlines: true})
+
+	m := &Message{
+		ShortMessage: "line one\r\nline two",
+		FullMessage:  "stack trace:\r\n  at foo\r\n  at bar",
+	}
+	if err := c.WriteMessage(m); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if strings.Contains(fields["short_message"].(string), "\r") {
+		t.Fatalf("short_message still contains \\r: %q", fields["short_message"])
+	}
+	if strings.Contains(fields["full_message"].(string), "\r") {
+		t.Fatalf("full_message still contains \\r: %q", fields["full_message"])
+	}
+	if fields["short_message"] != "line one\nline two" {
+		t.Fatalf("got %q", fields["short_message"])
+	}
+}
@@ -0,0 +1,39 @@
+package gelf
+
+import "time"
+
+// Ticker is the subset of *time.Ticker a Client needs.
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Clock abstracts time so timing-dependent features (self-reporting
+// today; rate limiting, TTLs, and similar later) can be tested without
+// real sleeps. Config.Clock defaults to realClock, which defers to the
+// time package.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct{ t *time.Ticker }
+
+func (r realTicker) C() <-chan time.Time { return r.t.C }
+func (r realTicker) Stop()               { r.t.Stop() }
+
+// clock returns the client's configured Clock, or realClock if unset.
+func (c *Client) clock() Clock {
+	if c.cfg.Clock != nil {
+		return c.cfg.Clock
+	}
+	return realClock{}
+}
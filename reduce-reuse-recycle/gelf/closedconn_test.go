@@ -0,0 +1,24 @@
+package gelf
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestWriteAfterConnClosedReturnsWrappedErrClosed(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	err := c.WriteMessage(&Message{ShortMessage: "x"})
+	if err == nil {
+		t.Fatalf("expected an error writing to a closed conn")
+	}
+	if !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("got %v, want errors.Is(err, net.ErrClosed)", err)
+	}
+}
@@ -0,0 +1,98 @@
+package gelf
+
+import (
+	"context"
+	"strings"
+)
+
+// coalesceBatch holds an open run of same-level messages awaiting
+// combination into one GELF event, per Config.CoalesceWindow and
+// Config.CoalesceLevel.
+type coalesceBatch struct {
+	first     *Message
+	lines     []string
+	count     int
+	stopTimer chan struct{}
+}
+
+// tryCoalesce buffers m if Config.CoalesceWindow is set and m.Level
+// matches Config.CoalesceLevel, reporting whether m was absorbed into
+// the pending batch rather than needing to be sent right away. A
+// message at any other level flushes and closes the current batch
+// first, so relative ordering toward the wire is preserved.
+func (c *Client) tryCoalesce(m *Message) bool {
+	if c.cfg.CoalesceWindow <= 0 {
+		return false
+	}
+
+	c.coalesceMu.Lock()
+	defer c.coalesceMu.Unlock()
+
+	if int(m.Level) != c.cfg.CoalesceLevel {
+		c.flushCoalesceLocked()
+		return false
+	}
+
+	if c.coalesce == nil {
+		c.coalesce = &coalesceBatch{
+			first:     m,
+			lines:     []string{m.ShortMessage},
+			count:     1,
+			stopTimer: make(chan struct{}),
+		}
+		// The ticker is created here, synchronously, rather than inside
+		// the goroutine below: otherwise a caller advancing a fake
+		// clock right after this call could race the goroutine's own
+		// clock.NewTicker and the tick would have nowhere to land.
+		ticker := c.clock().NewTicker(c.cfg.CoalesceWindow)
+		c.wg.Add(1)
+		go c.runCoalesceTimer(ticker, c.coalesce.stopTimer)
+		return true
+	}
+
+	c.coalesce.lines = append(c.coalesce.lines, m.ShortMessage)
+	c.coalesce.count++
+	return true
+}
+
+// runCoalesceTimer flushes the batch that opened it once window
+// elapses with nothing to close it sooner, so a run of coalesced
+// messages doesn't wait forever for a non-matching message that never
+// arrives. It also flushes on Close, so nothing buffered is lost at
+// shutdown.
+func (c *Client) runCoalesceTimer(ticker Ticker, stop chan struct{}) {
+	defer c.wg.Done()
+	defer ticker.Stop()
+
+	select {
+	case <-ticker.C():
+		c.flushCoalesce()
+	case <-stop:
+	case <-c.done:
+		c.flushCoalesce()
+	}
+}
+
+// flushCoalesce sends the pending coalesced batch, if any, as a single
+// combined GELF event.
+func (c *Client) flushCoalesce() {
+	c.coalesceMu.Lock()
+	defer c.coalesceMu.Unlock()
+	c.flushCoalesceLocked()
+}
+
+func (c *Client) flushCoalesceLocked() {
+	batch := c.coalesce
+	if batch == nil {
+		return
+	}
+	c.coalesce = nil
+	close(batch.stopTimer)
+
+	combined := *batch.first
+	combined.FullMessage = strings.Join(batch.lines, "\n")
+	combined.ensureExtra()
+	combined.Extra["_coalesced_count"] = batch.count
+
+	c.writeMessage(context.Background(), &combined, &c.Stats)
+}
@@ -0,0 +1,102 @@
+package gelf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestCoalesceCombinesConsecutiveSameLevelMessages(t *testing.T) {
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Clock:              clk,
+		CoalesceWindow:     time.Second,
+		CoalesceLevel:      7,
+	})
+
+	for _, msg := range []string{"tick", "tick", "tick"} {
+		if err := c.WriteMessage(&Message{ShortMessage: msg, Level: 7}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("expected nothing sent yet, got %d packets", len(conn.Packets()))
+	}
+
+	// A non-matching level flushes the pending batch immediately, then
+	// sends itself.
+	if err := c.WriteMessage(&Message{ShortMessage: "boom", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 2 {
+		t.Fatalf("got %d packets, want 2 (coalesced batch + the flushing message)", len(packets))
+	}
+
+	var coalesced map[string]interface{}
+	if err := json.Unmarshal(packets[0], &coalesced); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if coalesced["short_message"] != "tick" {
+		t.Fatalf("got short_message=%v, want %q", coalesced["short_message"], "tick")
+	}
+	if coalesced["full_message"] != "tick\ntick\ntick" {
+		t.Fatalf("got full_message=%q, want %q", coalesced["full_message"], "tick\ntick\ntick")
+	}
+	if got, want := coalesced["_coalesced_count"], float64(3); got != want {
+		t.Fatalf("got _coalesced_count=%v, want %v", got, want)
+	}
+
+	var flusher map[string]interface{}
+	if err := json.Unmarshal(packets[1], &flusher); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if flusher["short_message"] != "boom" {
+		t.Fatalf("got short_message=%v, want %q", flusher["short_message"], "boom")
+	}
+}
+
+func TestCoalesceFlushesAtWindowBoundaryWithoutANewMessage(t *testing.T) {
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Clock:              clk,
+		CoalesceWindow:     time.Minute,
+		CoalesceLevel:      6,
+	})
+	defer c.Close()
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hb", Level: 6}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("expected nothing sent before the window elapses")
+	}
+
+	clk.Advance(time.Minute)
+	waitForPackets(t, conn, 1)
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got, want := decoded["_coalesced_count"], float64(1); got != want {
+		t.Fatalf("got _coalesced_count=%v, want %v", got, want)
+	}
+}
+
+func TestCoalesceDisabledSendsImmediately(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "x", Level: 7}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if len(conn.Packets()) != 1 {
+		t.Fatalf("got %d packets, want 1 (no coalescing configured)", len(conn.Packets()))
+	}
+}
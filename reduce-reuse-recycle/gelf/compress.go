@@ -0,0 +1,161 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+)
+
+// compressionLevel returns the gzip/zlib level a Client should
+// compress with: the configured level, or gzip.DefaultCompression if
+// unset. zlib shares the same level constants as gzip.
+func (c *Client) compressionLevel() int {
+	if c.cfg.CompressionLevel == 0 {
+		return gzip.DefaultCompression
+	}
+	return c.cfg.CompressionLevel
+}
+
+// compressionType returns the Config.CompressionType a Client should
+// compress with: CompressionGzip, the zero value, unless overridden.
+func (c *Client) compressionType() CompressionType {
+	return c.cfg.CompressionType
+}
+
+// newCompressWriter returns a compressWriter of kind, writing to w at
+// level -- the seam compressAll, compressStreaming, and the pooled
+// compressor in pool.go all build on so neither has to know which
+// underlying package it's dealing with.
+func newCompressWriter(kind CompressionType, w io.Writer, level int) (compressWriter, error) {
+	if kind == CompressionZlib {
+		return zlib.NewWriterLevel(w, level)
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+
+// compressAll compresses b as kind at level in one pass, returning the
+// whole compressed payload.
+func compressAll(b []byte, kind CompressionType, level int) ([]byte, error) {
+	var buf bytes.Buffer
+	zw, err := newCompressWriter(kind, &buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// streamingInputBlock is the size of the pieces compressStreaming feeds
+// to the gzip writer between checks for completed chunk-sized output.
+// Smaller than maxChunkSize so output has a chance to cross a chunk
+// boundary mid-message rather than only at the very end.
+const streamingInputBlock = 4096
+
+// compressStreaming compresses b as kind at level, calling fn with
+// every full maxChunkSize block of compressed output as soon as it's
+// available, plus a final (possibly shorter) block once compression
+// finishes. No call to fn carries more than maxChunkSize bytes, and
+// only the last call may carry fewer: calling the compressor's Flush
+// at each boundary to force a shorter intermediate block would produce
+// nothing a GELF server reassembling a stream could use any sooner,
+// since the server needs the whole compressed stream to decode anything. So
+// compressStreaming never flushes early - it only ever hands fn a
+// short block once zw.Close has drained everything flate still had
+// buffered.
+//
+// Every slice handed to fn is chunk-sized rather than message-sized,
+// so a caller that forwards each one to the network (or otherwise
+// releases it) as it arrives never needs to hold the whole compressed
+// payload in one contiguous slice of its own. GELF's chunk header
+// declares the total chunk count up front, though, so sending still
+// can't start on the wire until every block has been produced here.
+func compressStreaming(b []byte, kind CompressionType, level, maxChunkSize int, fn func([]byte) error) error {
+	var buf bytes.Buffer
+	zw, err := newCompressWriter(kind, &buf, level)
+	if err != nil {
+		return err
+	}
+
+	drainFull := func() error {
+		for buf.Len() >= maxChunkSize {
+			chunk := make([]byte, maxChunkSize)
+			buf.Read(chunk)
+			if err := fn(chunk); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for off := 0; off < len(b); off += streamingInputBlock {
+		end := off + streamingInputBlock
+		if end > len(b) {
+			end = len(b)
+		}
+		if _, err := zw.Write(b[off:end]); err != nil {
+			return err
+		}
+		if err := drainFull(); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := drainFull(); err != nil {
+		return err
+	}
+	if buf.Len() > 0 {
+		last := make([]byte, buf.Len())
+		buf.Read(last)
+		if err := fn(last); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compressStreamingBounded runs compressStreaming on its own goroutine
+// and hands each produced block to sink on the caller's goroutine,
+// connected by a channel sized to hold at most limit bytes' worth of
+// blocks. Once that many bytes are buffered waiting for sink to catch
+// up, the compression goroutine blocks on its next block rather than
+// racing ahead of a slow sink (or a misconfigured tiny maxChunkSize on
+// a huge message) and growing memory without bound. limit values
+// smaller than maxSize still allow exactly one block in flight, so
+// compression always makes forward progress.
+func compressStreamingBounded(b []byte, kind CompressionType, level, maxSize, limit int, sink func([]byte) error) error {
+	blocks := limit / maxSize
+	if blocks < 1 {
+		blocks = 1
+	}
+
+	ch := make(chan []byte, blocks)
+	done := make(chan error, 1)
+	go func() {
+		done <- compressStreaming(b, kind, level, maxSize, func(p []byte) error {
+			ch <- p
+			return nil
+		})
+		close(ch)
+	}()
+
+	for p := range ch {
+		if err := sink(p); err != nil {
+			for range ch {
+				// Drain the rest so the compression goroutine, which
+				// may be blocked sending its next block, doesn't leak.
+			}
+			<-done
+			return err
+		}
+	}
+	return <-done
+}
@@ -0,0 +1,184 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func decompress(t *testing.T, b []byte) []byte {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading gzip stream: %v", err)
+	}
+	return out
+}
+
+func TestCompressStreamingRoundTrips(t *testing.T) {
+	payload := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 5000) // ~230KB
+
+	var got bytes.Buffer
+	err := compressStreaming(payload, CompressionGzip, gzip.DefaultCompression, defaultMaxChunkSize, func(chunk []byte) error {
+		if len(chunk) > defaultMaxChunkSize {
+			t.Fatalf("chunk of %d bytes exceeds max %d", len(chunk), defaultMaxChunkSize)
+		}
+		got.Write(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("compressStreaming: %v", err)
+	}
+
+	if out := decompress(t, got.Bytes()); !bytes.Equal(out, payload) {
+		t.Fatalf("round trip mismatch: got %d bytes, want %d", len(out), len(payload))
+	}
+}
+
+func TestCompressStreamingOnlyFinalChunkIsShort(t *testing.T) {
+	// Several payload sizes, chosen so the compressed output lands at,
+	// just under, and just over multiples of the chunk size.
+	for _, n := range []int{1000, 50000, 100000, 100037, defaultMaxChunkSize * 3} {
+		payload := make([]byte, n)
+		for i := range payload {
+			payload[i] = byte(i) // incompressible-ish, so output size tracks input size
+		}
+
+		var sizes []int
+		err := compressStreaming(payload, CompressionGzip, gzip.DefaultCompression, defaultMaxChunkSize, func(chunk []byte) error {
+			sizes = append(sizes, len(chunk))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("compressStreaming(n=%d): %v", n, err)
+		}
+
+		for i, sz := range sizes {
+			if i < len(sizes)-1 && sz != defaultMaxChunkSize {
+				t.Fatalf("n=%d: chunk %d has %d bytes, want exactly %d (only the final chunk may be short)", n, i, sz, defaultMaxChunkSize)
+			}
+			if sz == 0 {
+				t.Fatalf("n=%d: chunk %d is empty", n, i)
+			}
+		}
+	}
+}
+
+func TestCompressStreamingBoundedRoundTrips(t *testing.T) {
+	payload := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog. "), 5000) // ~230KB
+
+	var got bytes.Buffer
+	err := compressStreamingBounded(payload, CompressionGzip, gzip.DefaultCompression, defaultMaxChunkSize, defaultMaxChunkSize*2, func(chunk []byte) error {
+		got.Write(chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("compressStreamingBounded: %v", err)
+	}
+	if out := decompress(t, got.Bytes()); !bytes.Equal(out, payload) {
+		t.Fatalf("round trip mismatch: got %d bytes, want %d", len(out), len(payload))
+	}
+}
+
+func TestCompressStreamingBoundedBlocksCompressionOnASlowSink(t *testing.T) {
+	payload := make([]byte, defaultMaxChunkSize*20)
+	for i := range payload {
+		payload[i] = byte(i) // incompressible-ish, so compression produces many chunk-sized blocks
+	}
+
+	release := make(chan struct{})
+	var sinkCalls atomic.Int32
+
+	done := make(chan error, 1)
+	go func() {
+		done <- compressStreamingBounded(payload, CompressionGzip, gzip.DefaultCompression, defaultMaxChunkSize, defaultMaxChunkSize*2, func(chunk []byte) error {
+			sinkCalls.Add(1)
+			<-release
+			return nil
+		})
+	}()
+
+	// With the sink stalled on its very first call, a payload this much
+	// bigger than the limit has nowhere for the rest of its compressed
+	// output to go: compression must not be able to finish underneath
+	// it, unlike the unbounded collector used when MaxInFlightBytes is
+	// unset, which never blocks on its sink at all.
+	select {
+	case <-done:
+		t.Fatalf("compressStreamingBounded finished without the slow sink ever unblocking it")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := sinkCalls.Load(); got != 1 {
+		t.Fatalf("got %d sink calls before the slow sink blocked it, want exactly 1", got)
+	}
+
+	stopDraining := make(chan struct{})
+	defer close(stopDraining)
+	go func() {
+		for {
+			select {
+			case release <- struct{}{}:
+			case <-stopDraining:
+				return
+			}
+		}
+	}()
+
+	if err := <-done; err != nil {
+		t.Fatalf("compressStreamingBounded: %v", err)
+	}
+}
+
+func BenchmarkCompress200KB(b *testing.B) {
+	payload := make([]byte, 200*1024)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	b.Run("whole-buffer", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := compressAll(payload, CompressionGzip, gzip.DefaultCompression); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("streaming", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			err := compressStreaming(payload, CompressionGzip, gzip.DefaultCompression, defaultMaxChunkSize, func([]byte) error { return nil })
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkClientFirstWritesCold(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		c := newClient(&memConn{}, testAddr, Config{})
+		for j := 0; j < 8; j++ {
+			if err := c.WriteMessage(&Message{ShortMessage: "benchmark warm-up payload"}); err != nil {
+				b.Fatalf("WriteMessage: %v", err)
+			}
+		}
+	}
+}
+
+func BenchmarkClientFirstWritesWarm(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		c := newClient(&memConn{}, testAddr, Config{})
+		c.Warm(8)
+		for j := 0; j < 8; j++ {
+			if err := c.WriteMessage(&Message{ShortMessage: "benchmark warm-up payload"}); err != nil {
+				b.Fatalf("WriteMessage: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package gelf
+
+import (
+	"compress/gzip"
+	"fmt"
+)
+
+// CompressionType selects the algorithm Client uses to compress GELF
+// payloads before sending. The zero value, CompressionGzip, is what
+// Graylog expects by default; CompressionZlib is also part of the GELF
+// spec and can be smaller for small payloads, whose per-message
+// overhead gzip's extra framing makes relatively more expensive.
+type CompressionType int
+
+const (
+	// CompressionGzip, the zero value, compresses with compress/gzip.
+	CompressionGzip CompressionType = iota
+
+	// CompressionZlib compresses with compress/zlib instead.
+	CompressionZlib
+)
+
+// ParseCompressionLevel maps a human-friendly compression level name,
+// as you'd write in YAML or an env var, to the corresponding
+// compress/gzip constant. "none" maps to gzip.NoCompression, which is
+// 0; Config.CompressionLevel reads 0 as gzip.DefaultCompression, so
+// that value in Config.CompressionLevel compresses at the default
+// level rather than not at all.
+func ParseCompressionLevel(s string) (int, error) {
+	switch s {
+	case "none":
+		return gzip.NoCompression, nil
+	case "best-speed":
+		return gzip.BestSpeed, nil
+	case "best-compression":
+		return gzip.BestCompression, nil
+	case "default":
+		return gzip.DefaultCompression, nil
+	case "huffman-only":
+		return gzip.HuffmanOnly, nil
+	default:
+		return 0, fmt.Errorf("gelf: unknown compression level %q", s)
+	}
+}
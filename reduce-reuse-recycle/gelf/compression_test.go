@@ -0,0 +1,39 @@
+package gelf
+
+import (
+	"compress/gzip"
+	"testing"
+)
+
+func TestParseCompressionLevel(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want int
+	}{
+		{"none", "none", gzip.NoCompression},
+		{"best-speed", "best-speed", gzip.BestSpeed},
+		{"best-compression", "best-compression", gzip.BestCompression},
+		{"default", "default", gzip.DefaultCompression},
+		{"huffman-only", "huffman-only", gzip.HuffmanOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCompressionLevel(tt.in)
+			if err != nil {
+				t.Fatalf("ParseCompressionLevel(%q): %v", tt.in, err)
+			}
+			if got != tt.want {
+				t.Fatalf("ParseCompressionLevel(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCompressionLevelInvalid(t *testing.T) {
+	for _, in := range []string{"", "fast", "BEST-SPEED"} {
+		if _, err := ParseCompressionLevel(in); err == nil {
+			t.Fatalf("ParseCompressionLevel(%q): expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func TestCompressionTypeZlibProducesZlibFramedOutput(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		CompressionType: CompressionZlib,
+		AutoCompress:    true,
+	})
+
+	payload := bytes.Repeat([]byte("x"), 2000)
+	if err := c.WriteMessage(&Message{ShortMessage: string(payload)}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1", len(packets))
+	}
+	got := packets[0]
+	if !looksZlib(got) {
+		t.Fatalf("got packet starting %#v, want a zlib header", got[:2])
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(got))
+	if err != nil {
+		t.Fatalf("zlib.NewReader: %v", err)
+	}
+	defer zr.Close()
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if decoded["short_message"] != string(payload) {
+		t.Fatalf("got short_message %v, want the original payload", decoded["short_message"])
+	}
+}
+
+func TestDecompressPayloadRecognizesZlib(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write([]byte("hello, gelf")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got, err := decompressPayload(buf.Bytes())
+	if err != nil {
+		t.Fatalf("decompressPayload: %v", err)
+	}
+	if string(got) != "hello, gelf" {
+		t.Fatalf("got %q, want %q", got, "hello, gelf")
+	}
+}
+
+func TestDecompressPayloadStillHandlesUncompressed(t *testing.T) {
+	got, err := decompressPayload([]byte(`{"short_message":"hi"}`))
+	if err != nil {
+		t.Fatalf("decompressPayload: %v", err)
+	}
+	if string(got) != `{"short_message":"hi"}` {
+		t.Fatalf("got %q, want unchanged input", got)
+	}
+}
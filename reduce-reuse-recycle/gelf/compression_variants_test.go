@@ -0,0 +1,126 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// benchmarkPayloadSizes spans the range a real workload mixes: small
+// control messages, a typical log line, and an oversized one that
+// would force multiple GELF chunks.
+var benchmarkPayloadSizes = []int{64, 512, 4096, 32 * 1024}
+
+func benchmarkPayloads() [][]byte {
+	r := rand.New(rand.NewSource(1))
+	payloads := make([][]byte, len(benchmarkPayloadSizes))
+	for i, n := range benchmarkPayloadSizes {
+		p := make([]byte, n)
+		r.Read(p)
+		payloads[i] = p
+	}
+	return payloads
+}
+
+func averageBenchmarkPayloadSize() int64 {
+	var total int64
+	for _, n := range benchmarkPayloadSizes {
+		total += int64(n)
+	}
+	return total / int64(len(benchmarkPayloadSizes))
+}
+
+// reuseCompressor is the single-mutex strategy this benchmark measures
+// against compressAll's allocate-fresh-every-call baseline and
+// Client.compressAllPooled's per-goroutine pool: one gzip.Writer over
+// one buffer, shared by every caller and serialized behind mu. Nothing
+// in Client uses this -- compressAllPooled's pool gives every
+// goroutine its own compressor instead of making them all wait on one
+// -- but it's what "reuse" names in this package's reduce/reuse/recycle
+// framing, and without it here the pool's benefit over naive mutual
+// exclusion has no baseline to show against.
+type reuseCompressor struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	zw  *gzip.Writer
+}
+
+func newReuseCompressor(level int) (*reuseCompressor, error) {
+	rc := &reuseCompressor{}
+	zw, err := gzip.NewWriterLevel(&rc.buf, level)
+	if err != nil {
+		return nil, err
+	}
+	rc.zw = zw
+	return rc, nil
+}
+
+func (rc *reuseCompressor) compress(b []byte) ([]byte, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.buf.Reset()
+	rc.zw.Reset(&rc.buf)
+	if _, err := rc.zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := rc.zw.Close(); err != nil {
+		return nil, err
+	}
+	out := make([]byte, rc.buf.Len())
+	copy(out, rc.buf.Bytes())
+	return out, nil
+}
+
+// benchmarkConcurrentCompression drives compress with concurrency
+// goroutines per CPU, cycling through a mix of payload sizes and
+// discarding the result, so the benchmark isolates each strategy's own
+// allocation and contention behavior rather than any I/O cost.
+func benchmarkConcurrentCompression(b *testing.B, concurrency int, compress func([]byte) ([]byte, error)) {
+	payloads := benchmarkPayloads()
+	b.SetBytes(averageBenchmarkPayloadSize())
+	b.ReportAllocs()
+	b.SetParallelism(concurrency)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			out, err := compress(payloads[i%len(payloads)])
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.Discard.Write(out)
+			i++
+		}
+	})
+}
+
+// BenchmarkCompressionVariants runs reduce (compressAll's allocate-per-call
+// baseline), reuse (a single compressor behind a mutex), and recycle
+// (Client.compressAllPooled's per-goroutine pool) through the same
+// concurrent, mixed-size workload, so allocs/op, B/op, and MB/s (via
+// -benchmem) can be read side by side instead of compared across
+// separate benchmark runs with different conditions.
+func BenchmarkCompressionVariants(b *testing.B) {
+	level := gzip.DefaultCompression
+
+	b.Run("reduce/compressAll", func(b *testing.B) {
+		benchmarkConcurrentCompression(b, 8, func(p []byte) ([]byte, error) {
+			return compressAll(p, CompressionGzip, level)
+		})
+	})
+
+	b.Run("reuse/mutex", func(b *testing.B) {
+		rc, err := newReuseCompressor(level)
+		if err != nil {
+			b.Fatal(err)
+		}
+		benchmarkConcurrentCompression(b, 8, rc.compress)
+	})
+
+	b.Run("recycle/pool", func(b *testing.B) {
+		c := newClient(&memConn{}, testAddr, Config{})
+		benchmarkConcurrentCompression(b, 8, c.compressAllPooled)
+	})
+}
@@ -0,0 +1,80 @@
+package gelf
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func isGzipped(b []byte) bool {
+	return len(b) >= 2 && b[0] == 0x1f && b[1] == 0x8b
+}
+
+func TestCompressionWindow(t *testing.T) {
+	cfg := Config{CompressionThreshold: 100, CompressionMaxSize: 1000}
+
+	tests := []struct {
+		name       string
+		size       int
+		compressed bool
+	}{
+		{"below threshold", 50, false},
+		{"in window", 500, true},
+		{"above ceiling", 2000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &memConn{}
+			c := newClient(conn, testAddr, cfg)
+			payload := bytes.Repeat([]byte("a"), tt.size)
+
+			if err := c.dispatch(context.Background(), payload, &c.Stats); err != nil {
+				t.Fatalf("dispatch: %v", err)
+			}
+
+			got := isGzipped(conn.Packets()[0])
+			if got != tt.compressed {
+				t.Fatalf("got compressed=%v, want %v", got, tt.compressed)
+			}
+		})
+	}
+}
+
+func TestAutoCompressAppliesDefaultWindowAndReassembles(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		compressed bool
+	}{
+		{"below default threshold", defaultAutoCompressThreshold - 1, false},
+		{"in default window", defaultAutoCompressThreshold + 1000, true},
+		{"above default ceiling", defaultAutoCompressMaxSize + 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &memConn{}
+			c := newClient(conn, testAddr, Config{AutoCompress: true})
+			payload := bytes.Repeat([]byte("a"), tt.size)
+
+			if err := c.dispatch(context.Background(), payload, &c.Stats); err != nil {
+				t.Fatalf("dispatch: %v", err)
+			}
+
+			packets := conn.Packets()
+			got := isGzipped(packets[0])
+			if got != tt.compressed {
+				t.Fatalf("got compressed=%v, want %v", got, tt.compressed)
+			}
+
+			reassembled, err := ReassembleChunks(packets)
+			if err != nil {
+				t.Fatalf("ReassembleChunks: %v", err)
+			}
+			if !bytes.Equal(reassembled, payload) {
+				t.Fatalf("reassembled payload does not match original")
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package gelf
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestStatsCompressionRatioReflectsPayloadCompressibility(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{})
+
+	compressible := &Message{ShortMessage: string(bytes.Repeat([]byte("a"), 5000))}
+	if err := c.WriteMessage(compressible); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	highRatio := c.Stats.CompressionRatio()
+	if highRatio <= 1 {
+		t.Fatalf("got ratio %v for highly-compressible payload, want > 1", highRatio)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	random := make([]byte, 5000)
+	rng.Read(random)
+	poor := &Message{ShortMessage: string(random)}
+	for i := 0; i < 10; i++ {
+		if err := c.WriteMessage(poor); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	lowRatio := c.Stats.CompressionRatio()
+	if lowRatio >= highRatio {
+		t.Fatalf("got ratio %v after poorly-compressible payloads, want < %v", lowRatio, highRatio)
+	}
+}
+
+func TestStatsBytesSentTracksUncompressedAndWireBytesSeparately(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{})
+
+	compressible := &Message{ShortMessage: string(bytes.Repeat([]byte("a"), 5000))}
+	if err := c.WriteMessage(compressible); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	uncompressed := c.Stats.UncompressedBytes.Load()
+	wire := c.Stats.BytesSent.Load()
+	if uncompressed == 0 {
+		t.Fatalf("got UncompressedBytes=0, want nonzero")
+	}
+	if wire == 0 {
+		t.Fatalf("got BytesSent=0, want nonzero")
+	}
+	if wire >= uncompressed {
+		t.Fatalf("got BytesSent=%d >= UncompressedBytes=%d, want wire bytes smaller for a highly-compressible payload", wire, uncompressed)
+	}
+}
@@ -0,0 +1,72 @@
+package gelf
+
+import (
+	"io"
+	"sync"
+)
+
+// crashRing retains up to size of the most recently sent Messages, for
+// Client.DumpRecent to write out after a crash. It's a fixed-size
+// circular buffer: once full, each add overwrites the oldest entry.
+type crashRing struct {
+	mu   sync.Mutex
+	buf  []Message
+	next int
+	full bool
+}
+
+func newCrashRing(size int) *crashRing {
+	return &crashRing{buf: make([]Message, size)}
+}
+
+func (r *crashRing) add(m Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf[r.next] = m
+	r.next++
+	if r.next == len(r.buf) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// snapshot returns the retained Messages in the order they were
+// added, oldest first.
+func (r *crashRing) snapshot() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		out := make([]Message, r.next)
+		copy(out, r.buf[:r.next])
+		return out
+	}
+
+	out := make([]Message, len(r.buf))
+	n := copy(out, r.buf[r.next:])
+	copy(out[n:], r.buf[:r.next])
+	return out
+}
+
+// DumpRecent writes every Message currently retained by
+// Config.CrashDumpSize's ring buffer to w, oldest first, as one GELF
+// JSON object per line. Wiring this up to an actual crash handler
+// (signal.Notify, or a deferred recover in main) is left to the
+// caller -- DumpRecent only does the writing. A no-op returning nil if
+// CrashDumpSize was never set.
+func (c *Client) DumpRecent(w io.Writer) error {
+	if c.crashRing == nil {
+		return nil
+	}
+	for _, m := range c.crashRing.snapshot() {
+		b, err := m.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		b = append(b, '\n')
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClientDumpRecentWritesInOrderBeforeEviction(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, CrashDumpSize: 5})
+
+	for i := 0; i < 3; i++ {
+		if err := c.WriteMessage(&Message{ShortMessage: fmt.Sprintf("event %d", i)}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := c.DumpRecent(&buf); err != nil {
+		t.Fatalf("DumpRecent: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d dumped lines, want 3", len(lines))
+	}
+	for i, line := range lines {
+		var evt map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &evt); err != nil {
+			t.Fatalf("line %d: Unmarshal: %v", i, err)
+		}
+		if got, want := evt["short_message"], fmt.Sprintf("event %d", i); got != want {
+			t.Fatalf("line %d: got short_message=%v, want %q", i, got, want)
+		}
+	}
+}
+
+func TestClientDumpRecentEvictsOldestOnceFull(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, CrashDumpSize: 3})
+
+	for i := 0; i < 5; i++ {
+		if err := c.WriteMessage(&Message{ShortMessage: fmt.Sprintf("event %d", i)}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := c.DumpRecent(&buf); err != nil {
+		t.Fatalf("DumpRecent: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d dumped lines, want 3", len(lines))
+	}
+	want := []string{"event 2", "event 3", "event 4"}
+	for i, line := range lines {
+		var evt map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &evt); err != nil {
+			t.Fatalf("line %d: Unmarshal: %v", i, err)
+		}
+		if got := evt["short_message"]; got != want[i] {
+			t.Fatalf("line %d: got short_message=%v, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestClientDumpRecentNoOpWithoutCrashDumpSize(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "event"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := c.DumpRecent(&buf); err != nil {
+		t.Fatalf("DumpRecent: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("got %q, want nothing dumped without CrashDumpSize", buf.String())
+	}
+}
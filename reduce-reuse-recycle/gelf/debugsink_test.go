@@ -0,0 +1,92 @@
+package gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+// syncBuffer wraps a bytes.Buffer with a mutex so it's safe to pass as
+// Config.DebugSink from a concurrent test without a data race.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte{}, b.buf.Bytes()...)
+}
+
+func TestDebugSinkReceivesMessageDroppedBySampling(t *testing.T) {
+	sink := &syncBuffer{}
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		SampleRates:        map[int]float64{6: 0},
+		DebugSink:          sink,
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "dropped", Level: 6}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("got %d packets sent, want 0 -- SampleRates should have dropped this message", len(conn.Packets()))
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimRight(sink.Bytes(), "\n"), &decoded); err != nil {
+		t.Fatalf("Unmarshal debug sink output: %v (got %q)", err, sink.String())
+	}
+	if decoded["short_message"] != "dropped" {
+		t.Fatalf("got %v, want short_message %q", decoded, "dropped")
+	}
+}
+
+func TestDebugSinkOffByDefault(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if len(conn.Packets()) != 1 {
+		t.Fatalf("got %d packets, want 1", len(conn.Packets()))
+	}
+}
+
+func TestDebugSinkWritesEachMessageOnItsOwnLine(t *testing.T) {
+	sink := &syncBuffer{}
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		DebugSink:          sink,
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "first"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := c.WriteMessage(&Message{ShortMessage: "second"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	lines := bytes.Split(bytes.TrimRight(sink.Bytes(), "\n"), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2 (got %q)", len(lines), sink.String())
+	}
+}
@@ -0,0 +1,78 @@
+package gelf
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// dedupSetSizeDefault bounds a dedupSampler's memory when
+// Config.DedupSetSize isn't set.
+const dedupSetSizeDefault = 256
+
+// dedupEntry tracks one distinct ShortMessage hash's current window.
+type dedupEntry struct {
+	windowEnd time.Time
+	seenCount int64
+}
+
+// dedupSampler implements Config.DedupSampleRates: it reports whether
+// a ShortMessage is the first occurrence of its hash within the
+// configured window, and how many times that hash has been seen so
+// far in the window otherwise. Like recentIDSet, it's a fixed-capacity
+// ring plus a map for lookup, evicting the oldest tracked hash once
+// full.
+type dedupSampler struct {
+	mu      sync.Mutex
+	clock   Clock
+	window  time.Duration
+	hashes  []uint64
+	entries map[uint64]*dedupEntry
+	next    int
+}
+
+func newDedupSampler(size int, window time.Duration, clock Clock) *dedupSampler {
+	if size <= 0 {
+		size = dedupSetSizeDefault
+	}
+	return &dedupSampler{
+		clock:   clock,
+		window:  window,
+		hashes:  make([]uint64, size),
+		entries: make(map[uint64]*dedupEntry, size),
+	}
+}
+
+// hashShortMessage reduces a ShortMessage to a fixed-size key for the
+// dedup set, so an arbitrarily large message string never becomes the
+// thing retained in memory.
+func hashShortMessage(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// observe reports whether short is the first occurrence of its hash in
+// the current window, along with the running count of occurrences
+// seen in that window so far (including this one). A zero window
+// means every call is a first occurrence.
+func (d *dedupSampler) observe(short string) (first bool, seenCount int64) {
+	h := hashShortMessage(short)
+	now := d.clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.entries[h]; ok && now.Before(e.windowEnd) {
+		e.seenCount++
+		return false, e.seenCount
+	}
+
+	if old := d.hashes[d.next]; old != 0 {
+		delete(d.entries, old)
+	}
+	d.hashes[d.next] = h
+	d.next = (d.next + 1) % len(d.hashes)
+	d.entries[h] = &dedupEntry{windowEnd: now.Add(d.window), seenCount: 1}
+	return true, 1
+}
@@ -0,0 +1,104 @@
+package gelf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDedupSampleRatesAlwaysSendsFirstOccurrence(t *testing.T) {
+	conn := &memConn{}
+	clock := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		DedupSampleRates:   map[int]float64{3: 0},
+		DedupWindow:        time.Minute,
+		Clock:              clock,
+		Rand:               func() float64 { return 1 }, // never passes a sampling check
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "disk full", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if got := len(conn.Packets()); got != 1 {
+		t.Fatalf("got %d packets, want 1: the first occurrence must always be sent", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := c.WriteMessage(&Message{ShortMessage: "disk full", Level: 3}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+	if got := len(conn.Packets()); got != 1 {
+		t.Fatalf("got %d packets, want still 1: a zero rate should sample away every repeat", got)
+	}
+
+	if err := c.WriteMessage(&Message{ShortMessage: "out of memory", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if got := len(conn.Packets()); got != 2 {
+		t.Fatalf("got %d packets, want 2: a distinct message's first occurrence must also always be sent", got)
+	}
+}
+
+func TestDedupSampleRatesAttachesSeenCountToRepeats(t *testing.T) {
+	conn := &memConn{}
+	clock := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		DedupSampleRates:   map[int]float64{3: 1}, // always send repeats too
+		DedupWindow:        time.Minute,
+		Clock:              clock,
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := c.WriteMessage(&Message{ShortMessage: "disk full", Level: 3}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 3 {
+		t.Fatalf("got %d packets, want 3", len(packets))
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal(packets[0], &first); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if _, ok := first["_seen_count"]; ok {
+		t.Fatalf("first occurrence unexpectedly got _seen_count: %v", first["_seen_count"])
+	}
+
+	var third map[string]interface{}
+	if err := json.Unmarshal(packets[2], &third); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got, want := third["_seen_count"], float64(3); got != want {
+		t.Fatalf("got _seen_count=%v, want %v", got, want)
+	}
+}
+
+func TestDedupSampleRatesStartsFreshWindowAfterExpiry(t *testing.T) {
+	conn := &memConn{}
+	clock := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		DedupSampleRates:   map[int]float64{3: 0},
+		DedupWindow:        time.Minute,
+		Clock:              clock,
+		Rand:               func() float64 { return 1 },
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "disk full", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if err := c.WriteMessage(&Message{ShortMessage: "disk full", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if got := len(conn.Packets()); got != 2 {
+		t.Fatalf("got %d packets, want 2: a new window's first occurrence must be sent again", got)
+	}
+}
@@ -0,0 +1,44 @@
+package gelf
+
+import "testing"
+
+func TestDescribeReflectsNonDefaultSettings(t *testing.T) {
+	tr := &memTransport{}
+	c := newClient(nil, testAddr, Config{
+		DisableCompression: true,
+		SignKey:            []byte("super-secret-key"),
+		SampleRates:        map[int]float64{6: 0.5},
+		Transport:          tr,
+	})
+
+	d := c.Describe()
+
+	if d["compression_disabled"] != "true" {
+		t.Fatalf("got compression_disabled=%q, want true", d["compression_disabled"])
+	}
+	if d["signed"] != "true" {
+		t.Fatalf("got signed=%q, want true", d["signed"])
+	}
+	for _, v := range d {
+		if v == "super-secret-key" {
+			t.Fatalf("Describe leaked SignKey contents: %v", d)
+		}
+	}
+	if d["sampling"] != "enabled" {
+		t.Fatalf("got sampling=%q, want enabled", d["sampling"])
+	}
+	if d["transport"] != "*gelf.memTransport" {
+		t.Fatalf("got transport=%q, want the configured Transport's type", d["transport"])
+	}
+}
+
+func TestDescribeDefaultsToUDPTransport(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+	d := c.Describe()
+	if d["transport"] != "udp" {
+		t.Fatalf("got transport=%q, want udp", d["transport"])
+	}
+	if d["signed"] != "false" {
+		t.Fatalf("got signed=%q, want false", d["signed"])
+	}
+}
@@ -0,0 +1,26 @@
+package gelf
+
+import "net"
+
+// DialUDP binds a UDP socket to local and resolves remote, for
+// callers that need to control the outbound source address or port --
+// e.g. a firewalled environment where GELF traffic must originate
+// from a specific port range. Pass an empty host in local (e.g.
+// ":9001") to bind any interface on that port. The returned conn and
+// addr are ready to feed into NewClientWithConn.
+func DialUDP(local, remote string) (net.PacketConn, net.Addr, error) {
+	localAddr, err := net.ResolveUDPAddr("udp", local)
+	if err != nil {
+		return nil, nil, err
+	}
+	remoteAddr, err := net.ResolveUDPAddr("udp", remote)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, err := net.ListenUDP("udp", localAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conn, remoteAddr, nil
+}
@@ -0,0 +1,121 @@
+package gelf
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDialUDPBindsRequestedSourcePort(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP (fake server): %v", err)
+	}
+	defer server.Close()
+
+	conn, remoteAddr, err := DialUDP("127.0.0.1:0", server.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer conn.Close()
+
+	localPort := conn.LocalAddr().(*net.UDPAddr).Port
+	if localPort == 0 {
+		t.Fatalf("expected DialUDP to bind a concrete port, got 0")
+	}
+
+	c := NewClientWithConn(conn, remoteAddr, Config{DisableCompression: true})
+	defer c.Close()
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	buf := make([]byte, 1024)
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	n, srcAddr, err := server.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("ReadFromUDP: %v", err)
+	}
+	if n == 0 {
+		t.Fatalf("expected a nonempty packet")
+	}
+	if srcAddr.Port != localPort {
+		t.Fatalf("got source port %d, want the bound port %d", srcAddr.Port, localPort)
+	}
+}
+
+// writeBufferRecordingConn is a net.PacketConn test double recording
+// SetWriteBuffer calls, for asserting on Config.WriteBufferBytes
+// without a real socket.
+type writeBufferRecordingConn struct {
+	net.PacketConn
+	setBytes int
+}
+
+func (w *writeBufferRecordingConn) SetWriteBuffer(bytes int) error {
+	w.setBytes = bytes
+	return nil
+}
+
+func TestApplyWriteBufferBytesSetsConfiguredSize(t *testing.T) {
+	conn := &writeBufferRecordingConn{}
+	applyWriteBufferBytes(conn, Config{WriteBufferBytes: 1 << 20})
+	if conn.setBytes != 1<<20 {
+		t.Fatalf("got SetWriteBuffer(%d), want %d", conn.setBytes, 1<<20)
+	}
+}
+
+func TestApplyWriteBufferBytesNoOpWhenUnconfigured(t *testing.T) {
+	conn := &writeBufferRecordingConn{}
+	applyWriteBufferBytes(conn, Config{})
+	if conn.setBytes != 0 {
+		t.Fatalf("got SetWriteBuffer(%d), want no call", conn.setBytes)
+	}
+}
+
+func TestApplyWriteBufferBytesIgnoresUnsupportedConn(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer server.Close()
+
+	type noSetWriteBuffer struct{ net.PacketConn }
+	conn := noSetWriteBuffer{server}
+
+	applyWriteBufferBytes(conn, Config{WriteBufferBytes: 1 << 20})
+}
+
+func TestDialWithRetrySucceedsAfterTransientFailures(t *testing.T) {
+	attempts := 0
+	err := dialWithRetry(Config{DialRetries: 3}, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("dialWithRetry: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("got %d attempts, want 3", attempts)
+	}
+}
+
+func TestDialWithRetryGivesUpAfterExhaustingRetries(t *testing.T) {
+	attempts := 0
+	wantErr := errors.New("still not ready")
+	err := dialWithRetry(Config{DialRetries: 2}, func() error {
+		attempts++
+		return wantErr
+	})
+	if err != wantErr {
+		t.Fatalf("got %v, want %v", err, wantErr)
+	}
+	if attempts != 3 {
+		t.Fatalf("got %d attempts, want 3 (1 initial + 2 retries)", attempts)
+	}
+}
@@ -0,0 +1,64 @@
+package gelf
+
+// DropReason identifies why a message never reached the network, for
+// Config.OnDrop.
+type DropReason int
+
+const (
+	// DropReasonSampled is a message thinned by Config.SampleRates.
+	DropReasonSampled DropReason = iota + 1
+
+	// DropReasonDedupSampled is a repeat of an already-seen
+	// ShortMessage thinned by Config.DedupSampleRates.
+	DropReasonDedupSampled
+
+	// DropReasonQueueOverflow is a message an AsyncClient's bounded
+	// send queue, or its retry queue, couldn't hold.
+	DropReasonQueueOverflow
+
+	// DropReasonTTL is a message an AsyncClient discarded because it
+	// sat queued longer than Config.MessageTTL.
+	DropReasonTTL
+
+	// DropReasonQuota is a message a QuotaClient rejected because
+	// Config.QuotaBytes was exhausted for the current Config.QuotaWindow.
+	DropReasonQuota
+
+	// DropReasonCircuitOpen is reserved for a circuit-breaker wrapper;
+	// nothing in this package trips it yet.
+	DropReasonCircuitOpen
+)
+
+// String returns the lowercase_with_underscores name used in
+// diagnostics and tests; unrecognized values (zero, or anything added
+// to the enum later without a case here) report as "unknown" rather
+// than a bare integer.
+func (r DropReason) String() string {
+	switch r {
+	case DropReasonSampled:
+		return "sampled"
+	case DropReasonDedupSampled:
+		return "dedup_sampled"
+	case DropReasonQueueOverflow:
+		return "queue_overflow"
+	case DropReasonTTL:
+		return "ttl"
+	case DropReasonQuota:
+		return "quota"
+	case DropReasonCircuitOpen:
+		return "circuit_open"
+	default:
+		return "unknown"
+	}
+}
+
+// notifyDrop calls cfg.OnDrop, if set. Every drop site increments its
+// own Stats counter itself, immediately before or after calling this;
+// notifyDrop only handles the optional callback, so a caller that
+// wants the drop counted but has no message in hand yet (or already
+// counted it) isn't forced to route through here.
+func (cfg Config) notifyDrop(reason DropReason, m *Message) {
+	if cfg.OnDrop != nil {
+		cfg.OnDrop(reason, m)
+	}
+}
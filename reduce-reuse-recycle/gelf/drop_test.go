@@ -0,0 +1,163 @@
+package gelf
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// dropRecorder collects OnDrop calls for assertion, guarded by a mutex
+// since AsyncClient's sender and retry loops can call it concurrently.
+type dropRecorder struct {
+	mu      sync.Mutex
+	reasons []DropReason
+}
+
+func (d *dropRecorder) record(reason DropReason, m *Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.reasons = append(d.reasons, reason)
+}
+
+func (d *dropRecorder) get() []DropReason {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DropReason, len(d.reasons))
+	copy(out, d.reasons)
+	return out
+}
+
+func TestOnDropFiresWithSampledReason(t *testing.T) {
+	rec := &dropRecorder{}
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		SampleRates:        map[int]float64{6: 0},
+		OnDrop:             rec.record,
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi", Level: 6}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if got := rec.get(); len(got) != 1 || got[0] != DropReasonSampled {
+		t.Fatalf("got reasons %v, want [%v]", got, DropReasonSampled)
+	}
+}
+
+func TestOnDropFiresWithDedupSampledReason(t *testing.T) {
+	rec := &dropRecorder{}
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		DedupSampleRates:   map[int]float64{6: 0},
+		DedupWindow:        time.Minute,
+		Clock:              newFakeClock(time.Unix(0, 0)),
+		OnDrop:             rec.record,
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "repeat", Level: 6}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := c.WriteMessage(&Message{ShortMessage: "repeat", Level: 6}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if got := rec.get(); len(got) != 1 || got[0] != DropReasonDedupSampled {
+		t.Fatalf("got reasons %v, want exactly one %v", got, DropReasonDedupSampled)
+	}
+}
+
+func TestOnDropFiresWithQueueOverflowReason(t *testing.T) {
+	rec := &dropRecorder{}
+	conn := &blockingConn{release: make(chan struct{})}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, OnDrop: rec.record})
+	ac := NewAsyncClient(c, 1)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "in-flight"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := ac.WriteMessage(&Message{ShortMessage: "buffered"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := ac.WriteMessage(&Message{ShortMessage: "overflow"}); err != ErrQueueFull {
+		t.Fatalf("got %v, want ErrQueueFull", err)
+	}
+
+	close(conn.release)
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := rec.get(); len(got) != 1 || got[0] != DropReasonQueueOverflow {
+		t.Fatalf("got reasons %v, want exactly one %v", got, DropReasonQueueOverflow)
+	}
+}
+
+func TestOnDropFiresWithTTLReason(t *testing.T) {
+	rec := &dropRecorder{}
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		MessageTTL:         time.Second,
+		Clock:              clk,
+		OnDrop:             rec.record,
+	})
+	ac := NewAsyncClient(c, 4)
+
+	if err := ac.WriteMessage(&Message{ShortMessage: "stale"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	clk.Advance(2 * time.Second)
+
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if got := rec.get(); len(got) != 1 || got[0] != DropReasonTTL {
+		t.Fatalf("got reasons %v, want exactly one %v", got, DropReasonTTL)
+	}
+}
+
+func TestOnDropFiresWithQuotaReason(t *testing.T) {
+	rec := &dropRecorder{}
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		QuotaBytes:         10,
+		QuotaWindow:        time.Minute,
+		Clock:              clk,
+		OnDrop:             rec.record,
+	})
+	qc := NewQuotaClient(c)
+
+	for i := 0; i < 5; i++ {
+		if err := qc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+			t.Fatalf("WriteMessage %d: %v", i, err)
+		}
+	}
+
+	got := rec.get()
+	if len(got) == 0 {
+		t.Fatalf("expected at least one OnDrop call once the quota was exhausted")
+	}
+	for _, r := range got {
+		if r != DropReasonQuota {
+			t.Fatalf("got reason %v, want %v", r, DropReasonQuota)
+		}
+	}
+}
+
+func TestOnDropIsNilSafe(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		SampleRates:        map[int]float64{6: 0},
+	})
+	if err := c.WriteMessage(&Message{ShortMessage: "hi", Level: 6}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+}
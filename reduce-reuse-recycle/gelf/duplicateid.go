@@ -0,0 +1,57 @@
+package gelf
+
+import (
+	"fmt"
+	"sync"
+)
+
+// recentIDSetSize bounds the memory a Config.DetectDuplicateIDs
+// client spends remembering recent message IDs.
+const recentIDSetSize = 256
+
+// recentIDSet is a fixed-capacity ring of recently generated message
+// IDs, used to flag collisions. It's a diagnostic aid, not a
+// correctness mechanism, so a false negative after the ring wraps is
+// acceptable.
+type recentIDSet struct {
+	mu   sync.Mutex
+	ids  [][8]byte
+	seen map[[8]byte]struct{}
+	next int
+}
+
+func newRecentIDSet(size int) *recentIDSet {
+	return &recentIDSet{
+		ids:  make([][8]byte, size),
+		seen: make(map[[8]byte]struct{}, size),
+	}
+}
+
+// seenAndAdd reports whether id is already in the set, then records
+// it, evicting the oldest entry if the ring is full.
+func (s *recentIDSet) seenAndAdd(id [8]byte) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, dup := s.seen[id]
+
+	if old := s.ids[s.next]; old != [8]byte{} {
+		delete(s.seen, old)
+	}
+	s.ids[s.next] = id
+	s.seen[id] = struct{}{}
+	s.next = (s.next + 1) % len(s.ids)
+
+	return dup
+}
+
+// DuplicateMessageIDError is passed to Config.ErrorHandler when
+// Config.DetectDuplicateIDs catches a generated message ID matching
+// one still in its recent-IDs window.
+type DuplicateMessageIDError struct {
+	ID [8]byte
+}
+
+func (e *DuplicateMessageIDError) Error() string {
+	return fmt.Sprintf("gelf: duplicate message ID %x", e.ID)
+}
@@ -0,0 +1,37 @@
+package gelf
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDetectDuplicateIDsCallsErrorHandler(t *testing.T) {
+	conn := &memConn{}
+	var gotErrs []error
+	fixedID := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		DetectDuplicateIDs: true,
+		MessageIDFunc:      func() [8]byte { return fixedID },
+		ErrorHandler:       func(err error) { gotErrs = append(gotErrs, err) },
+	})
+
+	payload := make([]byte, defaultMaxChunkSize+100)
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(gotErrs) != 0 {
+		t.Fatalf("expected no error on first use, got %v", gotErrs)
+	}
+
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(gotErrs) != 1 {
+		t.Fatalf("expected one duplicate-ID error, got %v", gotErrs)
+	}
+	if _, ok := gotErrs[0].(*DuplicateMessageIDError); !ok {
+		t.Fatalf("got %T, want *DuplicateMessageIDError", gotErrs[0])
+	}
+}
@@ -0,0 +1,39 @@
+package gelf
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSendRecoversFromEMSGSIZE(t *testing.T) {
+	conn := &mtuConn{threshold: 800}
+	c := newClient(conn, testAddr, Config{})
+
+	payload := make([]byte, defaultMaxChunkSize) // fits in one datagram at the default size, but is past the 800-byte MTU threshold
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	if err := c.send(context.Background(), testAddr, payload); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if got := c.maxChunkSize(); got >= defaultMaxChunkSize {
+		t.Fatalf("expected chunk size to shrink below %d, got %d", defaultMaxChunkSize, got)
+	}
+
+	for _, p := range conn.Packets() {
+		if len(p) > conn.threshold {
+			t.Fatalf("packet of %d bytes exceeds MTU threshold %d", len(p), conn.threshold)
+		}
+	}
+
+	// The reduced size should stick for later messages.
+	sizeAfterFirst := c.maxChunkSize()
+	if err := c.send(context.Background(), testAddr, []byte("small message")); err != nil {
+		t.Fatalf("second send: %v", err)
+	}
+	if c.maxChunkSize() != sizeAfterFirst {
+		t.Fatalf("chunk size should persist across messages, got %d, want %d", c.maxChunkSize(), sizeAfterFirst)
+	}
+}
@@ -0,0 +1,44 @@
+package gelf
+
+// Encoder turns a Message into its wire-ready, pre-compression byte
+// representation. The default is GELF JSON (gelfJSONEncoder);
+// Config.Encoder lets callers substitute another wire format, such as
+// LogfmtEncoder, for downstream tooling that doesn't speak GELF. The
+// rest of the pipeline (compression, chunking, transport) is unaware
+// of which encoder produced the bytes.
+type Encoder interface {
+	Encode(m *Message) ([]byte, error)
+}
+
+// gelfJSONEncoder is the default Encoder, wrapping Message's own GELF
+// 1.1 JSON marshaling. If signKey is set, it also signs the payload --
+// see Config.SignKey.
+type gelfJSONEncoder struct {
+	autoPrefix   bool
+	signKey      []byte
+	keyTransform func(string) string
+}
+
+func (e gelfJSONEncoder) Encode(m *Message) ([]byte, error) {
+	b, err := m.encode(e.autoPrefix, e.keyTransform)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.signKey) == 0 {
+		return b, nil
+	}
+	return signGELF(b, e.signKey)
+}
+
+// encoder returns the client's configured Encoder, or the default
+// GELF JSON encoder if none was set.
+func (c *Client) encoder() Encoder {
+	if c.cfg.Encoder != nil {
+		return c.cfg.Encoder
+	}
+	return gelfJSONEncoder{
+		autoPrefix:   !c.cfg.DisableAutoPrefix,
+		signKey:      c.cfg.SignKey,
+		keyTransform: c.cfg.KeyTransform,
+	}
+}
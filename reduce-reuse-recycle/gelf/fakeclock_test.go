@@ -0,0 +1,60 @@
+package gelf
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a Clock test double: Now is fixed until moved forward
+// with Advance, and each Advance sends one tick to every ticker that
+// hasn't been stopped, whatever its period; a tick is dropped if the
+// ticker already has one pending.
+type fakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+func newFakeClock(start time.Time) *fakeClock {
+	return &fakeClock{now: start}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) Ticker {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{period: d, c: make(chan time.Time, 1)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d and sends one tick to every
+// ticker that hasn't been stopped, regardless of its period or of d.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for _, t := range f.tickers {
+		if t.stopped {
+			continue
+		}
+		select {
+		case t.c <- f.now:
+		default:
+		}
+	}
+}
+
+type fakeTicker struct {
+	period  time.Duration
+	c       chan time.Time
+	stopped bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+func (t *fakeTicker) Stop()               { t.stopped = true }
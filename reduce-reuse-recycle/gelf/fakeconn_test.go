@@ -0,0 +1,91 @@
+package gelf
+
+import (
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// memConn is a packetConn that records every packet written to it
+// instead of touching the network, for use across client tests.
+type memConn struct {
+	mu        sync.Mutex
+	packets   [][]byte
+	destAddrs []net.Addr
+	closed    bool
+}
+
+func (c *memConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return 0, net.ErrClosed
+	}
+	cp := make([]byte, len(b))
+	copy(cp, b)
+	c.packets = append(c.packets, cp)
+	c.destAddrs = append(c.destAddrs, addr)
+	return len(b), nil
+}
+
+func (c *memConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *memConn) Packets() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([][]byte, len(c.packets))
+	copy(out, c.packets)
+	return out
+}
+
+// PacketsWithAddrs is Packets, plus the destination each packet was
+// written to, for tests asserting which address a given write reached.
+func (c *memConn) PacketsWithAddrs() ([][]byte, []net.Addr) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	packets := make([][]byte, len(c.packets))
+	copy(packets, c.packets)
+	addrs := make([]net.Addr, len(c.destAddrs))
+	copy(addrs, c.destAddrs)
+	return packets, addrs
+}
+
+var testAddr net.Addr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 12201}
+
+// mtuConn behaves like memConn but rejects any packet larger than
+// threshold bytes with syscall.EMSGSIZE, simulating a link with an
+// unexpectedly small MTU.
+type mtuConn struct {
+	memConn
+	threshold int
+}
+
+func (c *mtuConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if len(b) > c.threshold {
+		return 0, &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EMSGSIZE)}
+	}
+	return c.memConn.WriteTo(b, addr)
+}
+
+// emsgsizeOnceConn rejects exactly its first WriteTo with
+// syscall.EMSGSIZE, regardless of the packet's size, then behaves like
+// memConn for every write after that -- simulating a path whose real
+// MTU has nothing to do with how big the write happened to be.
+type emsgsizeOnceConn struct {
+	memConn
+	failed bool
+}
+
+func (c *emsgsizeOnceConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if !c.failed {
+		c.failed = true
+		return 0, &net.OpError{Op: "write", Err: os.NewSyscallError("sendto", syscall.EMSGSIZE)}
+	}
+	return c.memConn.WriteTo(b, addr)
+}
@@ -0,0 +1,147 @@
+package gelf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// FieldOverflowStrategy selects how WriteMessage handles a message
+// whose field count exceeds Config.MaxFields. Every strategy that
+// keeps a "first N" subset orders fields by Message.Fields' insertion
+// order first -- it's the one caller-controlled field source this
+// package keeps ordered, see Fields -- followed by Message.Extra's
+// keys sorted alphabetically, since a map has no order of its own to
+// fall back on.
+type FieldOverflowStrategy int
+
+const (
+	// FieldOverflowReject, the zero value, makes WriteMessage return
+	// *ErrTooManyFields instead of sending a message whose field count
+	// exceeds Config.MaxFields.
+	FieldOverflowReject FieldOverflowStrategy = iota
+
+	// FieldOverflowDropExtra keeps the first Config.MaxFields fields
+	// and drops the rest. Lossy, but the message still sends.
+	FieldOverflowDropExtra
+
+	// FieldOverflowCollapseToJSON keeps the first Config.MaxFields-1
+	// fields and serializes every field beyond that into a single JSON
+	// object under one `_overflow` field, so no data is lost even
+	// though the message's field count stays at or under the limit.
+	FieldOverflowCollapseToJSON
+)
+
+// ErrTooManyFields is returned by WriteMessage when a message's field
+// count exceeds Config.MaxFields under FieldOverflowReject.
+type ErrTooManyFields struct {
+	Count int
+	Limit int
+}
+
+func (e *ErrTooManyFields) Error() string {
+	return fmt.Sprintf("gelf: message has %d fields, exceeds limit of %d", e.Count, e.Limit)
+}
+
+// overflowField is one Extra or Fields entry, flattened to a common
+// shape so orderedFields can sequence the two sources together.
+type overflowField struct {
+	key   string
+	value interface{}
+}
+
+// orderedFields returns m's Extra and Fields entries in the order
+// described on FieldOverflowStrategy: Fields first, in insertion
+// order, then Extra's keys sorted alphabetically.
+func orderedFields(m *Message) []overflowField {
+	out := make([]overflowField, 0, len(m.Extra)+fieldsLen(m.Fields))
+	if m.Fields != nil {
+		for i := 0; i < m.Fields.Len(); i++ {
+			k, v := m.Fields.at(i)
+			out = append(out, overflowField{key: k, value: v})
+		}
+	}
+
+	extraKeys := make([]string, 0, len(m.Extra))
+	for k := range m.Extra {
+		extraKeys = append(extraKeys, k)
+	}
+	sort.Strings(extraKeys)
+	for _, k := range extraKeys {
+		out = append(out, overflowField{key: k, value: m.Extra[k]})
+	}
+	return out
+}
+
+func fieldsLen(f *Fields) int {
+	if f == nil {
+		return 0
+	}
+	return f.Len()
+}
+
+// enforceMaxFields applies Config.MaxFields/FieldOverflowStrategy to
+// m, rewriting its Extra and clearing its Fields when the configured
+// strategy is lossy but not an outright rejection. A zero MaxFields
+// disables the check entirely.
+func (c *Client) enforceMaxFields(m *Message) error {
+	limit := c.cfg.MaxFields
+	if limit <= 0 {
+		return nil
+	}
+
+	total := len(m.Extra) + fieldsLen(m.Fields)
+	if total <= limit {
+		return nil
+	}
+
+	switch c.cfg.FieldOverflowStrategy {
+	case FieldOverflowDropExtra:
+		keepOverflowFields(m, orderedFields(m), limit)
+		return nil
+	case FieldOverflowCollapseToJSON:
+		return collapseOverflowFields(m, orderedFields(m), limit)
+	default:
+		return &ErrTooManyFields{Count: total, Limit: limit}
+	}
+}
+
+// keepOverflowFields rewrites m's Extra to the first limit fields of
+// all, dropping the rest, and clears Fields (its entries, if kept,
+// are now folded into Extra).
+func keepOverflowFields(m *Message, all []overflowField, limit int) {
+	kept := all
+	if len(kept) > limit {
+		kept = kept[:limit]
+	}
+	m.Extra = make(map[string]interface{}, len(kept))
+	for _, f := range kept {
+		m.Extra[f.key] = f.value
+	}
+	m.Fields = nil
+}
+
+// collapseOverflowFields rewrites m's Extra to the first limit-1
+// fields of all, plus one `_overflow` field holding every remaining
+// field JSON-encoded together, and clears Fields.
+func collapseOverflowFields(m *Message, all []overflowField, limit int) error {
+	keepCount := limit - 1
+	kept, overflow := all[:keepCount], all[keepCount:]
+
+	overflowMap := make(map[string]interface{}, len(overflow))
+	for _, f := range overflow {
+		overflowMap[f.key] = f.value
+	}
+	b, err := json.Marshal(overflowMap)
+	if err != nil {
+		return err
+	}
+
+	m.Extra = make(map[string]interface{}, len(kept)+1)
+	for _, f := range kept {
+		m.Extra[f.key] = f.value
+	}
+	m.Extra["_overflow"] = string(b)
+	m.Fields = nil
+	return nil
+}
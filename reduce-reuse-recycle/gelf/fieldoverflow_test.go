@@ -0,0 +1,120 @@
+package gelf
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestFieldOverflowRejectReturnsErrTooManyFields(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		MaxFields:          2,
+	})
+
+	err := c.WriteMessage(&Message{
+		ShortMessage: "hi",
+		Extra:        map[string]interface{}{"a": 1, "b": 2, "c": 3},
+	})
+
+	var tooMany *ErrTooManyFields
+	if !errors.As(err, &tooMany) {
+		t.Fatalf("got %v, want *ErrTooManyFields", err)
+	}
+	if tooMany.Count != 3 || tooMany.Limit != 2 {
+		t.Fatalf("got Count=%d Limit=%d, want Count=3 Limit=2", tooMany.Count, tooMany.Limit)
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("got %d packets sent, want 0 -- a rejected message must not be sent", len(conn.Packets()))
+	}
+}
+
+func TestFieldOverflowDropExtraKeepsFirstNInFieldsThenExtraOrder(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression:    true,
+		MaxFields:             2,
+		FieldOverflowStrategy: FieldOverflowDropExtra,
+	})
+
+	fields := NewFields(2).AddString("first", "keep-me").AddString("second", "keep-me-too")
+	if err := c.WriteMessage(&Message{
+		ShortMessage: "hi",
+		Extra:        map[string]interface{}{"z_dropped": "bye"},
+		Fields:       fields,
+	}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1", len(packets))
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(packets[0], &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if _, ok := decoded["_first"]; !ok {
+		t.Fatalf("got %v, want _first kept", decoded)
+	}
+	if _, ok := decoded["_second"]; !ok {
+		t.Fatalf("got %v, want _second kept", decoded)
+	}
+	if _, ok := decoded["_z_dropped"]; ok {
+		t.Fatalf("got %v, want _z_dropped dropped as overflow", decoded)
+	}
+}
+
+func TestFieldOverflowCollapseToJSONPreservesDroppedFields(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression:    true,
+		MaxFields:             2,
+		FieldOverflowStrategy: FieldOverflowCollapseToJSON,
+	})
+
+	fields := NewFields(1).AddString("first", "keep-me")
+	if err := c.WriteMessage(&Message{
+		ShortMessage: "hi",
+		Extra:        map[string]interface{}{"second": "overflow-me", "third": "overflow-me-too"},
+		Fields:       fields,
+	}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets, want 1", len(packets))
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(packets[0], &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := decoded["_first"]; got != "keep-me" {
+		t.Fatalf("got _first=%v, want %q", got, "keep-me")
+	}
+	raw, ok := decoded["_overflow"].(string)
+	if !ok {
+		t.Fatalf("got %v, want a string _overflow field", decoded["_overflow"])
+	}
+	var overflow map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &overflow); err != nil {
+		t.Fatalf("Unmarshal _overflow: %v", err)
+	}
+	if overflow["second"] != "overflow-me" || overflow["third"] != "overflow-me-too" {
+		t.Fatalf("got overflow %v, want both dropped fields preserved", overflow)
+	}
+}
+
+func TestMaxFieldsZeroDisablesTheCheck(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{
+		ShortMessage: "hi",
+		Extra:        map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4, "e": 5},
+	}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package gelf
+
+// fieldKind identifies which union member of a fieldEntry is valid.
+type fieldKind uint8
+
+const (
+	fieldKindString fieldKind = iota
+	fieldKindInt
+	fieldKindFloat
+	fieldKindBool
+)
+
+// fieldEntry is one key/value pair held by a Fields builder. Only one
+// of str/i/num/b is meaningful, per kind; storing common Go types
+// directly instead of behind interface{} avoids boxing them until a
+// Fields is actually flattened for encoding. i holds fieldKindInt's
+// value directly rather than boxing it through num (a float64): a
+// float64 can only represent an int64 exactly up to 2^53, and a
+// logging field is exactly the kind of value (an ID, a byte count, a
+// timestamp in nanoseconds) that routinely exceeds that.
+type fieldEntry struct {
+	key  string
+	kind fieldKind
+	str  string
+	i    int64
+	num  float64
+	b    bool
+}
+
+// Fields is a reusable, allocation-light alternative to building
+// Message.Extra as a map[string]interface{}. It's backed by a plain
+// slice, so adding a field never allocates a map or hashes a key, and
+// the typed Add* methods avoid boxing common Go types into
+// interface{} until the message is encoded. Reset and reuse a Fields
+// across many log calls to amortize its backing slice's allocation
+// away entirely.
+//
+// A Message may carry Extra, Fields, or both; set whichever is more
+// convenient at the call site.
+type Fields struct {
+	entries []fieldEntry
+}
+
+// NewFields returns a Fields builder with its backing slice
+// pre-sized for capacity entries.
+func NewFields(capacity int) *Fields {
+	return &Fields{entries: make([]fieldEntry, 0, capacity)}
+}
+
+// AddString appends a string-valued field and returns f, so calls can
+// be chained.
+func (f *Fields) AddString(key, val string) *Fields {
+	f.entries = append(f.entries, fieldEntry{key: key, kind: fieldKindString, str: val})
+	return f
+}
+
+// AddInt appends an int-valued field and returns f, so calls can be
+// chained.
+func (f *Fields) AddInt(key string, val int64) *Fields {
+	f.entries = append(f.entries, fieldEntry{key: key, kind: fieldKindInt, i: val})
+	return f
+}
+
+// AddFloat appends a float-valued field and returns f, so calls can be
+// chained.
+func (f *Fields) AddFloat(key string, val float64) *Fields {
+	f.entries = append(f.entries, fieldEntry{key: key, kind: fieldKindFloat, num: val})
+	return f
+}
+
+// AddBool appends a bool-valued field and returns f, so calls can be
+// chained.
+func (f *Fields) AddBool(key string, val bool) *Fields {
+	f.entries = append(f.entries, fieldEntry{key: key, kind: fieldKindBool, b: val})
+	return f
+}
+
+// Reset empties f so its backing slice can be reused for the next
+// message, without reallocating.
+func (f *Fields) Reset() {
+	f.entries = f.entries[:0]
+}
+
+// Len reports the number of fields currently held.
+func (f *Fields) Len() int {
+	return len(f.entries)
+}
+
+// at unboxes entry i into an interface{}, for callers (encode) that
+// need to hand it to something that expects one, such as
+// json.Marshal's map[string]interface{}.
+func (f *Fields) at(i int) (key string, value interface{}) {
+	e := f.entries[i]
+	switch e.kind {
+	case fieldKindString:
+		return e.key, e.str
+	case fieldKindInt:
+		return e.key, e.i
+	case fieldKindFloat:
+		return e.key, e.num
+	case fieldKindBool:
+		return e.key, e.b
+	default:
+		return e.key, nil
+	}
+}
@@ -0,0 +1,142 @@
+package gelf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFieldsBuilderEncodesAlongsideExtra(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	fields := NewFields(4).
+		AddString("request_id", "abc123").
+		AddInt("status", 200).
+		AddFloat("duration_ms", 12.5).
+		AddBool("cache_hit", true)
+
+	m := &Message{
+		ShortMessage: "request served",
+		Extra:        map[string]interface{}{"route": "/health"},
+		Fields:       fields,
+	}
+	if err := c.WriteMessage(m); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	want := map[string]interface{}{
+		"_request_id":  "abc123",
+		"_status":      float64(200),
+		"_duration_ms": 12.5,
+		"_cache_hit":   true,
+		"_route":       "/health",
+	}
+	for k, v := range want {
+		if decoded[k] != v {
+			t.Fatalf("got %s=%v, want %v (full message: %v)", k, decoded[k], v, decoded)
+		}
+	}
+}
+
+func TestFieldsResetReusesBackingSlice(t *testing.T) {
+	f := NewFields(2).AddString("a", "1").AddInt("b", 2)
+	if f.Len() != 2 {
+		t.Fatalf("got Len=%d, want 2", f.Len())
+	}
+	f.Reset()
+	if f.Len() != 0 {
+		t.Fatalf("got Len=%d after Reset, want 0", f.Len())
+	}
+	f.AddString("c", "3")
+	if f.Len() != 1 {
+		t.Fatalf("got Len=%d, want 1", f.Len())
+	}
+	key, val := f.at(0)
+	if key != "c" || val != "3" {
+		t.Fatalf("got %s=%v, want c=3", key, val)
+	}
+}
+
+func TestAddIntRoundTripsFullInt64Precision(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	const big int64 = 9007199254740993 // 2^53 + 1, outside float64's exact integer range
+	fields := NewFields(1).AddInt("big", big)
+	key, val := fields.at(0)
+	if key != "big" || val != big {
+		t.Fatalf("got %s=%v (%T), want big=%d (int64)", key, val, val, big)
+	}
+
+	m := &Message{Host: "worker-1", ShortMessage: "x", Fields: fields}
+	if err := c.WriteMessage(m); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var decoded struct {
+		Big int64 `json:"_big"`
+	}
+	if err := json.Unmarshal(conn.Packets()[0], &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if decoded.Big != big {
+		t.Fatalf("got _big=%d on the wire, want %d -- AddInt must not lose precision", decoded.Big, big)
+	}
+}
+
+func TestFieldsBuilderRejectsReservedIDField(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	m := &Message{ShortMessage: "x", Fields: NewFields(1).AddString("id", "nope")}
+	if err := c.WriteMessage(m); err == nil {
+		t.Fatalf("expected an error for a reserved field in Fields")
+	}
+}
+
+// BenchmarkWriteMessageWithMapExtra and BenchmarkWriteMessageWithFields
+// are meant to be compared with -benchmem, e.g.:
+//
+//	go test -bench 'WriteMessageWith' -benchmem
+//
+// to show the map-free Fields builder path allocating less per call.
+func BenchmarkWriteMessageWithMapExtra(b *testing.B) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		m := &Message{
+			ShortMessage: "request served",
+			Extra: map[string]interface{}{
+				"request_id":  "abc123",
+				"status":      200,
+				"duration_ms": 12.5,
+				"cache_hit":   true,
+			},
+		}
+		c.WriteMessage(m)
+	}
+}
+
+func BenchmarkWriteMessageWithFields(b *testing.B) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	fields := NewFields(4)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		fields.Reset()
+		fields.
+			AddString("request_id", "abc123").
+			AddInt("status", 200).
+			AddFloat("duration_ms", 12.5).
+			AddBool("cache_hit", true)
+		m := &Message{ShortMessage: "request served", Fields: fields}
+		c.WriteMessage(m)
+	}
+}
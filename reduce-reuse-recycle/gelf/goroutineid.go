@@ -0,0 +1,35 @@
+package gelf
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// currentGoroutineID parses the calling goroutine's ID out of its own
+// stack trace's "goroutine N [...]" header line. This relies on an
+// undocumented runtime output format rather than any public API, so
+// it's best-effort: a format change in a future Go release just makes
+// ok false, never a panic.
+func currentGoroutineID() (int, bool) {
+	buf := make([]byte, 64)
+	n := runtime.Stack(buf, false)
+	buf = buf[:n]
+
+	const prefix = "goroutine "
+	if !bytes.HasPrefix(buf, []byte(prefix)) {
+		return 0, false
+	}
+	buf = buf[len(prefix):]
+
+	end := bytes.IndexByte(buf, ' ')
+	if end < 0 {
+		return 0, false
+	}
+
+	id, err := strconv.Atoi(string(buf[:end]))
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,203 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPBatchTransport is a Transport for Graylog's bulk HTTP GELF
+// input, batching several messages' JSON bodies into one
+// gzip-compressed, newline-delimited POST instead of one HTTP request
+// per message. It flushes whenever BatchSize messages have
+// accumulated or BatchInterval has elapsed since the first message in
+// the current batch, whichever comes first.
+//
+// Unlike udpTransport, Send doesn't wait for the network: it buffers
+// the message and returns immediately, so a failed POST can't be
+// reported back to the Send call (or calls) that filled the batch --
+// Graylog's bulk endpoint accepts or rejects the whole request as one
+// unit, with no per-object feedback either. ErrorHandler, if set, is
+// called instead, once per failed flush.
+type HTTPBatchTransport struct {
+	url          string
+	httpClient   *http.Client
+	batchSize    int
+	interval     time.Duration
+	errorHandler func(error)
+
+	mu      sync.Mutex
+	pending [][]byte
+	timer   *time.Timer
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// HTTPBatchTransportConfig configures NewHTTPBatchTransport.
+type HTTPBatchTransportConfig struct {
+	// URL is the Graylog HTTP GELF input's bulk endpoint.
+	URL string
+
+	// HTTPClient sends the batch POSTs; http.DefaultClient is used if
+	// nil.
+	HTTPClient *http.Client
+
+	// BatchSize is how many messages accumulate before a flush. Less
+	// than 1 is treated as 1, meaning every message flushes in its
+	// own request -- still valid, just forfeits the batching benefit.
+	BatchSize int
+
+	// BatchInterval bounds how long a partial batch waits for
+	// BatchSize to fill before flushing anyway. Zero means no time
+	// bound: a partial batch waits for BatchSize messages, or Close.
+	BatchInterval time.Duration
+
+	// ErrorHandler, if set, is called with the error from a failed
+	// flush -- a POST that failed outright, or came back with a
+	// non-2xx status. Called on the goroutine sendBatch starts for
+	// that flush's POST; Close waits for it to return, so it must not
+	// block.
+	ErrorHandler func(error)
+}
+
+// NewHTTPBatchTransport starts an HTTPBatchTransport posting to
+// cfg.URL. Assign the result to Config.Transport to use it.
+func NewHTTPBatchTransport(cfg HTTPBatchTransportConfig) *HTTPBatchTransport {
+	client := cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	batchSize := cfg.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &HTTPBatchTransport{
+		url:          cfg.URL,
+		httpClient:   client,
+		batchSize:    batchSize,
+		interval:     cfg.BatchInterval,
+		errorHandler: cfg.ErrorHandler,
+	}
+}
+
+// Send reassembles packets -- the chunks of one GELF message -- back
+// into its JSON body and adds it to the current batch, flushing if
+// that fills it. It returns promptly; see the type doc comment for
+// why a failed flush can't be reported back through Send.
+func (t *HTTPBatchTransport) Send(packets [][]byte) error {
+	payload, err := ReassembleChunks(packets)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return fmt.Errorf("gelf: HTTPBatchTransport is closed")
+	}
+	t.pending = append(t.pending, payload)
+	if len(t.pending) == 1 && t.interval > 0 {
+		t.timer = time.AfterFunc(t.interval, t.flushOnTimer)
+	}
+	var batch [][]byte
+	if len(t.pending) >= t.batchSize {
+		batch = t.takeBatchLocked()
+	}
+	t.mu.Unlock()
+
+	if batch != nil {
+		t.sendBatch(batch)
+	}
+	return nil
+}
+
+// flushOnTimer is the BatchInterval callback; it flushes whatever is
+// pending even if BatchSize hasn't been reached.
+func (t *HTTPBatchTransport) flushOnTimer() {
+	t.mu.Lock()
+	batch := t.takeBatchLocked()
+	t.mu.Unlock()
+	if batch != nil {
+		t.sendBatch(batch)
+	}
+}
+
+// takeBatchLocked removes and returns whatever is pending, stopping
+// the flush timer if one is running. Callers must hold t.mu; it
+// returns nil if nothing is pending.
+func (t *HTTPBatchTransport) takeBatchLocked() [][]byte {
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if len(t.pending) == 0 {
+		return nil
+	}
+	batch := t.pending
+	t.pending = nil
+	return batch
+}
+
+// sendBatch POSTs batch as one newline-delimited, gzip-compressed
+// request body, asynchronously, so Send and flushOnTimer never block
+// on the network.
+func (t *HTTPBatchTransport) sendBatch(batch [][]byte) {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		if err := t.post(batch); err != nil && t.errorHandler != nil {
+			t.errorHandler(err)
+		}
+	}()
+}
+
+func (t *HTTPBatchTransport) post(batch [][]byte) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, p := range batch {
+		if _, err := zw.Write(p); err != nil {
+			return err
+		}
+		if _, err := zw.Write([]byte("\n")); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.url, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("gelf: http batch POST to %s: %w", t.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("gelf: http batch POST to %s: unexpected status %s", t.url, resp.Status)
+	}
+	return nil
+}
+
+// Close flushes whatever is pending and waits for every in-flight
+// POST, including the one it just triggered, to finish.
+func (t *HTTPBatchTransport) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	batch := t.takeBatchLocked()
+	t.mu.Unlock()
+
+	if batch != nil {
+		t.sendBatch(batch)
+	}
+	t.wg.Wait()
+	return nil
+}
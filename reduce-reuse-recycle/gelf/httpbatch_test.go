@@ -0,0 +1,173 @@
+package gelf
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingBulkHandler records how many newline-delimited GELF objects
+// arrived in each gzip-compressed request body it receives.
+type countingBulkHandler struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (h *countingBulkHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	zr, err := gzip.NewReader(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer zr.Close()
+
+	body, err := io.ReadAll(zr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var objects []string
+	for _, line := range strings.Split(strings.TrimRight(string(body), "\n"), "\n") {
+		if line != "" {
+			objects = append(objects, line)
+		}
+	}
+
+	h.mu.Lock()
+	h.batches = append(h.batches, objects)
+	h.mu.Unlock()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *countingBulkHandler) getBatches() [][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([][]string, len(h.batches))
+	copy(out, h.batches)
+	return out
+}
+
+func TestHTTPBatchTransportFlushesOnBatchSize(t *testing.T) {
+	handler := &countingBulkHandler{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	transport := NewHTTPBatchTransport(HTTPBatchTransportConfig{
+		URL:       srv.URL,
+		BatchSize: 3,
+	})
+	c := newClient(&memConn{}, testAddr, Config{DisableCompression: true, Transport: transport})
+
+	for i := 0; i < 6; i++ {
+		if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+			t.Fatalf("WriteMessage %d: %v", i, err)
+		}
+	}
+	if err := transport.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	batches := handler.getBatches()
+	if len(batches) != 2 {
+		t.Fatalf("got %d batches, want 2", len(batches))
+	}
+	for i, b := range batches {
+		if len(b) != 3 {
+			t.Fatalf("batch %d has %d objects, want 3", i, len(b))
+		}
+	}
+}
+
+func TestHTTPBatchTransportFlushesOnInterval(t *testing.T) {
+	handler := &countingBulkHandler{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	transport := NewHTTPBatchTransport(HTTPBatchTransportConfig{
+		URL:           srv.URL,
+		BatchSize:     100,
+		BatchInterval: 20 * time.Millisecond,
+	})
+	c := newClient(&memConn{}, testAddr, Config{DisableCompression: true, Transport: transport})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "lonely"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(handler.getBatches()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := transport.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	batches := handler.getBatches()
+	if len(batches) != 1 || len(batches[0]) != 1 {
+		t.Fatalf("got batches %v, want exactly one batch of one object", batches)
+	}
+}
+
+func TestHTTPBatchTransportCloseFlushesPartialBatch(t *testing.T) {
+	handler := &countingBulkHandler{}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	transport := NewHTTPBatchTransport(HTTPBatchTransportConfig{
+		URL:       srv.URL,
+		BatchSize: 100,
+	})
+	c := newClient(&memConn{}, testAddr, Config{DisableCompression: true, Transport: transport})
+
+	for i := 0; i < 2; i++ {
+		if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+			t.Fatalf("WriteMessage %d: %v", i, err)
+		}
+	}
+	if err := transport.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	batches := handler.getBatches()
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("got batches %v, want exactly one batch of two objects", batches)
+	}
+}
+
+func TestHTTPBatchTransportReportsFailedFlushViaErrorHandler(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	errs := make(chan error, 1)
+	transport := NewHTTPBatchTransport(HTTPBatchTransportConfig{
+		URL:       srv.URL,
+		BatchSize: 1,
+		ErrorHandler: func(err error) {
+			errs <- err
+		},
+	})
+	c := newClient(&memConn{}, testAddr, Config{DisableCompression: true, Transport: transport})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatal("got nil error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for ErrorHandler")
+	}
+	_ = transport.Close()
+}
@@ -0,0 +1,149 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// interopReceiver is a minimal GELF UDP receiver implementing the
+// chunk-reassembly and decompression steps of the GELF spec on its
+// own, independently of this package's ReassembleChunks. Validating a
+// client against it, rather than against our own reassembler, catches
+// header or ordering bugs a self-consistent round-trip test would
+// share between sender and receiver and so never see.
+type interopReceiver struct {
+	conn *net.UDPConn
+}
+
+func newInteropReceiver(t *testing.T) *interopReceiver {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	return &interopReceiver{conn: conn}
+}
+
+func (r *interopReceiver) addr() string { return r.conn.LocalAddr().String() }
+
+func (r *interopReceiver) close() { r.conn.Close() }
+
+// receiveOne reads datagrams until one complete GELF message has been
+// reassembled, then returns its decompressed JSON. A packet starting
+// with the GELF chunk magic (0x1e 0x0f) is one chunk of a multi-chunk
+// message -- an 8-byte message ID, a 1-byte sequence number, and a
+// 1-byte total chunk count, followed by that chunk's share of the
+// payload; anything else is a complete, unchunked message.
+func (r *interopReceiver) receiveOne(t *testing.T) []byte {
+	r.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	chunks := map[byte][]byte{}
+	var wantCount byte
+	buf := make([]byte, 65536)
+	for {
+		n, _, err := r.conn.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("ReadFromUDP: %v", err)
+		}
+		p := make([]byte, n)
+		copy(p, buf[:n])
+
+		if len(p) < 12 || p[0] != 0x1e || p[1] != 0x0f {
+			return decompressInterop(t, p)
+		}
+
+		seq, count := p[10], p[11]
+		wantCount = count
+		chunks[seq] = p[12:]
+		if byte(len(chunks)) == wantCount {
+			var joined []byte
+			for i := byte(0); i < wantCount; i++ {
+				joined = append(joined, chunks[i]...)
+			}
+			return decompressInterop(t, joined)
+		}
+	}
+}
+
+// decompressInterop gunzips b if it carries the gzip magic bytes,
+// otherwise returns it as-is -- GELF allows either.
+func decompressInterop(t *testing.T, b []byte) []byte {
+	if len(b) < 2 || b[0] != 0x1f || b[1] != 0x8b {
+		return b
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gzip read: %v", err)
+	}
+	return out
+}
+
+// incompressibleString returns n pseudo-random lowercase letters, so a
+// payload built from it stays larger than one chunk after gzip.
+func incompressibleString(n int) string {
+	r := rand.New(rand.NewSource(1))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte('a' + r.Intn(26))
+	}
+	return string(b)
+}
+
+// TestInteropReceiverParsesEachClientVariant sends real messages over
+// a real UDP socket to interopReceiver, covering single-chunk and
+// multi-chunk messages both uncompressed and gzip-compressed, and
+// asserts the receiver's independent reassembly agrees
+// with what was sent.
+func TestInteropReceiverParsesEachClientVariant(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping real-socket interop test in -short mode")
+	}
+
+	tests := []struct {
+		name  string
+		cfg   Config
+		short string
+	}{
+		{"single-chunk uncompressed", Config{DisableCompression: true}, "hello interop"},
+		{"single-chunk gzip", Config{}, "hello interop"},
+		{"multi-chunk uncompressed", Config{DisableCompression: true}, strings.Repeat("x", defaultMaxChunkSize*3)},
+		{"multi-chunk gzip", Config{}, incompressibleString(defaultMaxChunkSize * 3)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recv := newInteropReceiver(t)
+			defer recv.close()
+
+			c, err := NewClient(recv.addr(), tt.cfg)
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			defer c.Close()
+
+			if err := c.WriteMessage(&Message{ShortMessage: tt.short}); err != nil {
+				t.Fatalf("WriteMessage: %v", err)
+			}
+
+			got := recv.receiveOne(t)
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(got, &decoded); err != nil {
+				t.Fatalf("json.Unmarshal(%q): %v", got, err)
+			}
+			if decoded["short_message"] != tt.short {
+				t.Fatalf("got short_message %q, want %q", decoded["short_message"], tt.short)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+//go:build linux
+
+package gelf
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JournaldEncoder encodes a Message in the systemd journal native
+// protocol instead of GELF JSON: one "FIELD=value\n" line per field,
+// or, for a value containing a newline, the binary-length-framed form
+// the protocol requires for multiline values. Field names are
+// uppercased and GELF's leading underscore on Extra/Fields keys is
+// stripped, since journald has its own leading-underscore convention
+// for trusted fields and these aren't that.
+type JournaldEncoder struct{}
+
+// journaldPriority maps a GELF/syslog level to journald's PRIORITY
+// field, which uses the same 0-7 scale, so no translation is needed
+// beyond the field name.
+func journaldPriority(level int32) string {
+	return strconv.FormatInt(int64(level), 10)
+}
+
+func (JournaldEncoder) Encode(m *Message) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writeJournaldField(&buf, "MESSAGE", m.ShortMessage)
+	writeJournaldField(&buf, "PRIORITY", journaldPriority(m.Level))
+	if m.Host != "" {
+		writeJournaldField(&buf, "_HOSTNAME", m.Host)
+	}
+	if m.FullMessage != "" {
+		writeJournaldField(&buf, "MESSAGE_FULL", m.FullMessage)
+	}
+
+	fields := make(map[string]string)
+	for k, v := range m.Extra {
+		fields[journaldFieldName(k)] = fmt.Sprint(v)
+	}
+	if m.Fields != nil {
+		for i := 0; i < m.Fields.Len(); i++ {
+			k, v := m.Fields.at(i)
+			fields[journaldFieldName(k)] = fmt.Sprint(v)
+		}
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeJournaldField(&buf, k, fields[k])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// journaldFieldName upcases key and strips a GELF-style leading
+// underscore, since journald's own leading-underscore fields (like
+// _HOSTNAME above) are a different, protocol-trusted namespace than
+// GELF's Extra-field prefixing convention.
+func journaldFieldName(key string) string {
+	return strings.ToUpper(strings.TrimPrefix(key, "_"))
+}
+
+// writeJournaldField appends one field to buf in the journal native
+// protocol: "NAME=value\n" for a value with no embedded newline, or
+// journald's binary-length-framed form -- name, newline, little-endian
+// uint64 length, the raw value, trailing newline -- when it does,
+// since a bare "NAME=value\n" can't represent a value containing '\n'
+// unambiguously.
+func writeJournaldField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	var lenBuf [8]byte
+	putUint64LE(lenBuf[:], uint64(len(value)))
+	buf.Write(lenBuf[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+func putUint64LE(b []byte, v uint64) {
+	for i := 0; i < 8; i++ {
+		b[i] = byte(v >> (8 * i))
+	}
+}
+
+// JournaldTransport sends already-encoded journal-protocol datagrams
+// to the systemd journal's native socket (normally
+// /run/systemd/journal/socket) over unixgram, as an alternative sink
+// alongside -- not instead of -- a Client's usual GELF destination.
+// It implements Transport, but each Send call writes the single
+// datagram in packets[0] (journald framing isn't chunked the way GELF
+// is; build the encoded message with JournaldEncoder, which never
+// exceeds what one datagram can hold for realistic message sizes) and
+// ignores the rest.
+type JournaldTransport struct {
+	conn net.Conn
+}
+
+// NewJournaldTransport dials addr (typically
+// "/run/systemd/journal/socket") over unixgram.
+func NewJournaldTransport(addr string) (*JournaldTransport, error) {
+	conn, err := net.Dial("unixgram", addr)
+	if err != nil {
+		return nil, fmt.Errorf("gelf: dialing journald socket %s: %w", addr, err)
+	}
+	return &JournaldTransport{conn: conn}, nil
+}
+
+func (t *JournaldTransport) Send(packets [][]byte) error {
+	if len(packets) == 0 {
+		return nil
+	}
+	_, err := t.conn.Write(packets[0])
+	return err
+}
+
+func (t *JournaldTransport) Close() error { return t.conn.Close() }
@@ -0,0 +1,118 @@
+//go:build linux
+
+package gelf
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readJournaldFields parses a journal-protocol datagram back into a
+// name->value map, understanding both the "NAME=value\n" form and the
+// binary-length-framed multiline form, mirroring writeJournaldField.
+func readJournaldFields(t *testing.T, b []byte) map[string]string {
+	t.Helper()
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		nl := bytes.IndexByte(b, '\n')
+		if nl < 0 {
+			t.Fatalf("malformed journald datagram, no newline: %q", b)
+		}
+		line := b[:nl]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			b = b[nl+1:]
+			continue
+		}
+
+		// No '=' on this line: it's a field name followed by the
+		// binary-length-framed form.
+		name := string(line)
+		rest := b[nl+1:]
+		if len(rest) < 8 {
+			t.Fatalf("malformed journald datagram, truncated length prefix for %s", name)
+		}
+		var n uint64
+		for i := 0; i < 8; i++ {
+			n |= uint64(rest[i]) << (8 * i)
+		}
+		rest = rest[8:]
+		if uint64(len(rest)) < n+1 {
+			t.Fatalf("malformed journald datagram, truncated value for %s", name)
+		}
+		fields[name] = string(rest[:n])
+		b = rest[n+1:]
+	}
+	return fields
+}
+
+func TestJournaldEncoderFramesMultilineFields(t *testing.T) {
+	m := &Message{
+		ShortMessage: "boom",
+		FullMessage:  "line one\nline two",
+		Host:         "web-1",
+		Level:        3,
+		Extra:        map[string]interface{}{"_request_id": "abc123"},
+	}
+
+	b, err := JournaldEncoder{}.Encode(m)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	fields := readJournaldFields(t, b)
+	if got, want := fields["MESSAGE"], "boom"; got != want {
+		t.Fatalf("got MESSAGE=%q, want %q", got, want)
+	}
+	if got, want := fields["PRIORITY"], "3"; got != want {
+		t.Fatalf("got PRIORITY=%q, want %q", got, want)
+	}
+	if got, want := fields["_HOSTNAME"], "web-1"; got != want {
+		t.Fatalf("got _HOSTNAME=%q, want %q", got, want)
+	}
+	if got, want := fields["MESSAGE_FULL"], "line one\nline two"; got != want {
+		t.Fatalf("got MESSAGE_FULL=%q, want %q", got, want)
+	}
+	if got, want := fields["REQUEST_ID"], "abc123"; got != want {
+		t.Fatalf("got REQUEST_ID=%q, want %q", got, want)
+	}
+}
+
+func TestJournaldTransportSendsOverUnixgram(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "journal.socket")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("ListenUnixgram: %v", err)
+	}
+	defer listener.Close()
+	defer os.Remove(sock)
+
+	tr, err := NewJournaldTransport(sock)
+	if err != nil {
+		t.Fatalf("NewJournaldTransport: %v", err)
+	}
+	defer tr.Close()
+
+	c := newClient(&memConn{}, testAddr, Config{
+		DisableCompression: true,
+		Encoder:            JournaldEncoder{},
+		Transport:          tr,
+	})
+	if err := c.WriteMessage(&Message{ShortMessage: "via journald"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatalf("reading from mock journal socket: %v", err)
+	}
+
+	fields := readJournaldFields(t, buf[:n])
+	if got, want := fields["MESSAGE"], "via journald"; got != want {
+		t.Fatalf("got MESSAGE=%q, want %q", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package gelf
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SnakeCase is a ready-made Config.KeyTransform that converts a
+// CamelCase or PascalCase key (as Go struct field names naturally are)
+// into lowercase snake_case, the form most Graylog dashboards and
+// search queries expect. An underscore is inserted before an uppercase
+// letter that starts a new word: either the first uppercase letter
+// after a run of lowercase letters, or the last letter of a run of
+// uppercase letters immediately followed by a lowercase one (so an
+// acronym like "ID" in "UserID" or "HTTPStatus" stays together).
+// Keys that are already snake_case or lowercase pass through
+// unchanged.
+func SnakeCase(key string) string {
+	var b strings.Builder
+	b.Grow(len(key) + 4)
+	runes := []rune(key)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if !unicode.IsUpper(prev) || nextLower {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
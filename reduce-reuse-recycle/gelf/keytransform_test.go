@@ -0,0 +1,61 @@
+package gelf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSnakeCaseConvertsCamelCase(t *testing.T) {
+	tests := map[string]string{
+		"userID":        "user_id",
+		"UserID":        "user_id",
+		"HTTPStatus":    "http_status",
+		"Count":         "count",
+		"already_snake": "already_snake",
+	}
+	for in, want := range tests {
+		if got := SnakeCase(in); got != want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestKeyTransformAppliesToExtraKeys(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, KeyTransform: SnakeCase})
+
+	m := &Message{
+		ShortMessage: "event",
+		Extra:        map[string]interface{}{"UserID": "42", "RequestCount": 3},
+	}
+	if err := c.WriteMessage(m); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if _, ok := got["_user_id"]; !ok {
+		t.Fatalf("got %v, want a _user_id field", got)
+	}
+	if _, ok := got["_request_count"]; !ok {
+		t.Fatalf("got %v, want a _request_count field", got)
+	}
+}
+
+func TestKeyTransformStillCatchesReservedFields(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, KeyTransform: SnakeCase})
+
+	// "Id" snake-cases to "id", which auto-prefixing would turn into
+	// the reserved "_id" -- the reserved-field check must see the
+	// transformed key, not the original.
+	m := &Message{ShortMessage: "event", Extra: map[string]interface{}{"Id": "42"}}
+	if err := c.WriteMessage(m); err == nil {
+		t.Fatalf("expected a reserved-field error after KeyTransform produces %q", "id")
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("expected nothing sent for a reserved field")
+	}
+}
@@ -0,0 +1,68 @@
+package gelf
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LogfmtEncoder encodes a Message as logfmt (key=value pairs) instead
+// of GELF JSON, for downstream tooling that ingests logfmt. Extra
+// fields are emitted in sorted key order for deterministic output.
+type LogfmtEncoder struct{}
+
+func (LogfmtEncoder) Encode(m *Message) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writeLogfmtPair(&buf, "message", m.ShortMessage)
+	writeLogfmtPair(&buf, "level", fmt.Sprint(m.Level))
+	writeLogfmtPair(&buf, "host", m.Host)
+	if m.FullMessage != "" {
+		writeLogfmtPair(&buf, "full_message", m.FullMessage)
+	}
+
+	keys := make([]string, 0, len(m.Extra))
+	for k := range m.Extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeLogfmtPair(&buf, k, fmt.Sprint(m.Extra[k]))
+	}
+
+	if m.Fields != nil {
+		fieldKeys := make([]string, m.Fields.Len())
+		values := make(map[string]interface{}, m.Fields.Len())
+		for i := range fieldKeys {
+			k, v := m.Fields.at(i)
+			fieldKeys[i] = k
+			values[k] = v
+		}
+		sort.Strings(fieldKeys)
+		for _, k := range fieldKeys {
+			writeLogfmtPair(&buf, k, fmt.Sprint(values[k]))
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeLogfmtPair(buf *bytes.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	buf.WriteString(quoteLogfmtValue(value))
+}
+
+// quoteLogfmtValue quotes value if it's empty or contains characters
+// that would otherwise make it ambiguous to parse back (spaces, "=",
+// or '"'), escaping any embedded quotes.
+func quoteLogfmtValue(value string) string {
+	if value != "" && !strings.ContainsAny(value, " =\"") {
+		return value
+	}
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
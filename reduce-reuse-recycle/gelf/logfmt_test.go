@@ -0,0 +1,65 @@
+package gelf
+
+import "testing"
+
+func TestLogfmtEncoderQuoting(t *testing.T) {
+	tests := []struct {
+		name string
+		m    *Message
+		want string
+	}{
+		{
+			name: "plain values need no quoting",
+			m:    &Message{ShortMessage: "ok", Host: "h1"},
+			want: `message=ok level=0 host=h1`,
+		},
+		{
+			name: "value with a space is quoted",
+			m:    &Message{ShortMessage: "hello world", Host: "h1"},
+			want: `message="hello world" level=0 host=h1`,
+		},
+		{
+			name: "empty value is quoted",
+			m:    &Message{ShortMessage: "", Host: "h1"},
+			want: `message="" level=0 host=h1`,
+		},
+		{
+			name: "value containing an equals sign is quoted",
+			m:    &Message{ShortMessage: "a=b", Host: "h1"},
+			want: `message="a=b" level=0 host=h1`,
+		},
+		{
+			name: "embedded quotes are escaped",
+			m:    &Message{ShortMessage: `say "hi"`, Host: "h1"},
+			want: `message="say \"hi\"" level=0 host=h1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LogfmtEncoder{}.Encode(tt.m)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientEncoderOverride(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Encoder:            LogfmtEncoder{},
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	got := string(conn.Packets()[0])
+	if got[:len("message=hi")] != "message=hi" {
+		t.Fatalf("got %q, want logfmt-encoded packet", got)
+	}
+}
@@ -0,0 +1,204 @@
+package gelf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// gelfVersion is the only GELF spec version this client speaks.
+const gelfVersion = "1.1"
+
+// Message is a single GELF event. Extra carries the user-defined
+// fields; per the GELF spec these are encoded with a leading
+// underscore and must not collide with the reserved field names.
+type Message struct {
+	Host         string
+	ShortMessage string
+	FullMessage  string
+	Timestamp    time.Time
+	Level        int32
+	Extra        map[string]interface{}
+
+	// Fields is an allocation-light alternative to Extra for
+	// high-throughput call sites: see the Fields doc comment. If both
+	// are set, every key from each is encoded; Fields keys are applied
+	// after Extra's.
+	Fields *Fields
+
+	// File and Line identify the call site, emitted verbatim as
+	// _file/_line when nonzero. Callers that already know their call
+	// site (e.g. a logging wrapper) can set these directly to skip the
+	// runtime.Caller lookup Config.AddCaller would otherwise do.
+	File string
+	Line int
+}
+
+// normalizeNewlines converts "\r\n" to "\n", for Config.NormalizeNewlines.
+func normalizeNewlines(s string) string {
+	if !strings.Contains(s, "\r\n") {
+		return s
+	}
+	return strings.ReplaceAll(s, "\r\n", "\n")
+}
+
+// truncateFieldValue shortens s to at most max bytes, appending a
+// marker naming how many bytes were dropped, for Config.MaxFieldValueBytes.
+// It's a no-op if s already fits. The cut is byte-, not rune-,
+// precise, so truncating mid-rune can leave an invalid trailing
+// UTF-8 sequence in the result; encoding/json replaces that with
+// U+FFFD rather than erroring, which is an acceptable cost for a
+// size guard that exists specifically to bound worst-case input.
+func truncateFieldValue(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	dropped := len(s) - max
+	return fmt.Sprintf("%s…[truncated %d bytes]", s[:max], dropped)
+}
+
+// ensureExtra lazily allocates Extra so default-field injectors (the
+// client's stack trace, process, uptime and _seen_count fields, and
+// middleware such as InjectFields) always
+// have a map to write into instead of having to check for nil
+// themselves.
+func (m *Message) ensureExtra() {
+	if m.Extra == nil {
+		m.Extra = make(map[string]interface{})
+	}
+}
+
+// MarshalJSON encodes m as a GELF 1.1 JSON payload: the fixed fields
+// plus one underscore-prefixed entry per Extra key. It always
+// auto-prefixes; callers that need Config.DisableAutoPrefix go through
+// encode instead.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	return m.encode(true, nil)
+}
+
+// ErrReservedField is returned when Extra contains a key GELF reserves
+// for the server's own use. Graylog silently drops a message whose
+// payload has one of these rather than rejecting it, so callers need
+// this caught before the message ever reaches the wire.
+type ErrReservedField struct {
+	Key string
+}
+
+func (e *ErrReservedField) Error() string {
+	return fmt.Sprintf("gelf: Extra contains reserved field %q", e.Key)
+}
+
+// checkReservedFields rejects "id" and "_id" in Extra: GELF forbids an
+// "_id" field because it collides with the server's internal ID, and
+// "id" would become "_id" under auto-prefixing anyway. keyTransform,
+// if set, is applied before the check, so a transform that happens to
+// produce one of these names is still caught rather than reaching the
+// wire.
+func (m *Message) checkReservedFields(keyTransform func(string) string) error {
+	isReserved := func(key string) bool {
+		if keyTransform != nil {
+			key = keyTransform(key)
+		}
+		return key == "id" || key == "_id"
+	}
+	for key := range m.Extra {
+		if isReserved(key) {
+			return &ErrReservedField{Key: key}
+		}
+	}
+	if m.Fields != nil {
+		for i := 0; i < m.Fields.Len(); i++ {
+			key, _ := m.Fields.at(i)
+			if isReserved(key) {
+				return &ErrReservedField{Key: key}
+			}
+		}
+	}
+	return nil
+}
+
+// ErrMissingRequiredField is returned when a message is missing a
+// GELF field the spec requires. It's checked late -- after Host and
+// Timestamp have been defaulted and Config.TransformMessage has had a
+// chance to fill anything in -- so it only ever fires for a field a
+// caller (or a HostFunc returning "") genuinely left empty.
+type ErrMissingRequiredField struct {
+	Field string
+}
+
+func (e *ErrMissingRequiredField) Error() string {
+	return fmt.Sprintf("gelf: message is missing required field %q", e.Field)
+}
+
+// checkRequiredFields reports *ErrMissingRequiredField if m is missing
+// "host" or "short_message", the two GELF fields the spec requires a
+// message to carry.
+func (m *Message) checkRequiredFields() error {
+	if m.Host == "" {
+		return &ErrMissingRequiredField{Field: "host"}
+	}
+	if m.ShortMessage == "" {
+		return &ErrMissingRequiredField{Field: "short_message"}
+	}
+	return nil
+}
+
+// encode is MarshalJSON's implementation, parameterized on whether
+// Extra keys get an underscore prefix auto-added and on an optional
+// key-renaming transform (see Config.KeyTransform). keyTransform, when
+// set, runs before auto-prefixing, so it only ever sees the bare key.
+// Either way the prefixing is idempotent: a key that already starts
+// with "_" is never prefixed twice.
+func (m *Message) encode(autoPrefix bool, keyTransform func(string) string) ([]byte, error) {
+	if err := m.checkReservedFields(keyTransform); err != nil {
+		return nil, err
+	}
+
+	extraCount := len(m.Extra)
+	if m.Fields != nil {
+		extraCount += m.Fields.Len()
+	}
+	fields := make(map[string]interface{}, extraCount+6)
+	fields["version"] = gelfVersion
+	fields["host"] = m.Host
+	fields["short_message"] = m.ShortMessage
+	if m.FullMessage != "" {
+		fields["full_message"] = m.FullMessage
+	}
+	fields["timestamp"] = float64(m.Timestamp.UnixNano()) / float64(time.Second)
+	fields["level"] = m.Level
+
+	if m.File != "" {
+		fields["_file"] = m.File
+	}
+	if m.Line != 0 {
+		fields["_line"] = m.Line
+	}
+
+	for k, v := range m.Extra {
+		key := k
+		if keyTransform != nil {
+			key = keyTransform(key)
+		}
+		if autoPrefix && !strings.HasPrefix(key, "_") {
+			key = "_" + key
+		}
+		fields[key] = v
+	}
+	if m.Fields != nil {
+		for i := 0; i < m.Fields.Len(); i++ {
+			k, v := m.Fields.at(i)
+			key := k
+			if keyTransform != nil {
+				key = keyTransform(key)
+			}
+			if autoPrefix && !strings.HasPrefix(key, "_") {
+				key = "_" + key
+			}
+			fields[key] = v
+		}
+	}
+
+	return json.Marshal(fields)
+}
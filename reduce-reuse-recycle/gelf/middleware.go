@@ -0,0 +1,71 @@
+package gelf
+
+import "context"
+
+// MessageHandler processes one outgoing Message, typically by encoding
+// and sending it. The terminal handler in a Client's middleware chain
+// does exactly that; every Middleware wraps a MessageHandler to add
+// behavior around it.
+type MessageHandler func(ctx context.Context, m *Message) error
+
+// Middleware wraps a MessageHandler with additional behavior -- e.g.
+// redaction, field injection, or a checksum -- composing several such
+// concerns into one chain instead of growing WriteMessage's own body
+// with another conditional per feature. Config.Middleware lists the
+// chain to apply; Middleware[0] runs first (outermost), wrapping
+// everything after it, down to the terminal handler that performs the
+// actual encode+send.
+type Middleware func(next MessageHandler) MessageHandler
+
+// buildHandler composes cfg.Middleware around the terminal handler
+// that performs the real encode+send, in the order documented on
+// Middleware.
+func (c *Client) buildHandler() MessageHandler {
+	h := MessageHandler(func(ctx context.Context, m *Message) error {
+		return c.writeMessage(ctx, m, &c.Stats)
+	})
+	for i := len(c.cfg.Middleware) - 1; i >= 0; i-- {
+		h = c.cfg.Middleware[i](h)
+	}
+	return h
+}
+
+// RedactFields returns a Middleware that replaces the value of every
+// listed Extra key with a fixed placeholder before passing the message
+// on, for fields too sensitive to forward to Graylog (e.g. a token a
+// lower layer attached for its own diagnostics) without losing the
+// fact that the field was present.
+func RedactFields(keys ...string) Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return func(ctx context.Context, m *Message) error {
+			for _, k := range keys {
+				if _, ok := m.Extra[k]; ok {
+					m.ensureExtra()
+					m.Extra[k] = "[REDACTED]"
+				}
+			}
+			return next(ctx, m)
+		}
+	}
+}
+
+// InjectFields returns a Middleware that merges fields into every
+// message's Extra before passing it on, without overwriting a key the
+// message already set -- useful for constant, deployment-wide
+// metadata (a region, a build ID) that every feature downstream should
+// see without every caller having to set it itself.
+func InjectFields(fields map[string]interface{}) Middleware {
+	return func(next MessageHandler) MessageHandler {
+		return func(ctx context.Context, m *Message) error {
+			if len(fields) > 0 {
+				m.ensureExtra()
+				for k, v := range fields {
+					if _, ok := m.Extra[k]; !ok {
+						m.Extra[k] = v
+					}
+				}
+			}
+			return next(ctx, m)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package gelf
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestMiddlewareChainRunsInConfiguredOrder(t *testing.T) {
+	var order []string
+	record := func(name string) Middleware {
+		return func(next MessageHandler) MessageHandler {
+			return func(ctx context.Context, m *Message) error {
+				order = append(order, name)
+				return next(ctx, m)
+			}
+		}
+	}
+
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Middleware:         []Middleware{record("outer"), record("inner")},
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if got := []string{"outer", "inner"}; len(order) != 2 || order[0] != got[0] || order[1] != got[1] {
+		t.Fatalf("got call order %v, want %v", order, got)
+	}
+}
+
+func TestRedactFieldsReplacesMatchingExtraValues(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Middleware:         []Middleware{RedactFields("token")},
+	})
+
+	err := c.WriteMessage(&Message{
+		ShortMessage: "hi",
+		Extra:        map[string]interface{}{"token": "super-secret", "region": "us-east"},
+	})
+	if err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got["_token"] != "[REDACTED]" {
+		t.Fatalf("got _token=%v, want [REDACTED]", got["_token"])
+	}
+	if got["_region"] != "us-east" {
+		t.Fatalf("RedactFields touched an unlisted field: got _region=%v", got["_region"])
+	}
+}
+
+func TestInjectFieldsDoesNotOverwriteExistingKey(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		Middleware: []Middleware{InjectFields(map[string]interface{}{
+			"region": "us-east",
+			"env":    "prod",
+		})},
+	})
+
+	err := c.WriteMessage(&Message{
+		ShortMessage: "hi",
+		Extra:        map[string]interface{}{"region": "eu-west"},
+	})
+	if err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got["_region"] != "eu-west" {
+		t.Fatalf("InjectFields overwrote an existing field: got _region=%v, want eu-west", got["_region"])
+	}
+	if got["_env"] != "prod" {
+		t.Fatalf("got _env=%v, want prod", got["_env"])
+	}
+}
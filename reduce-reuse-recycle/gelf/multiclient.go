@@ -0,0 +1,221 @@
+package gelf
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// MultiClient fans a single WriteMessage out to every destination
+// Client it holds. Destinations keep their own Config, including
+// compression, so heterogeneous setups (one graylog input expecting
+// gzip, another expecting raw GELF) are supported directly: each
+// distinct compression configuration pays for its own encode pass.
+// Destinations that share an identical compression configuration are
+// grouped so that pass is only paid once and the result is reused
+// across their sends.
+type MultiClient struct {
+	clients []*Client
+}
+
+// NewMultiClient builds a MultiClient fanning out to clients.
+func NewMultiClient(clients ...*Client) *MultiClient {
+	return &MultiClient{clients: clients}
+}
+
+// compressionKey identifies clients that would make the same
+// compress-or-not decision, with the same result, for any given
+// payload. streamMode is included so a Client built with NewTCPClient
+// -- which, like dispatchToSkipping, never compresses or chunks --
+// never lands in the same group as a UDP Client with otherwise
+// identical compression settings; mixing the two would have the
+// shared-compression path below send gzip-framed bytes to a TCP input
+// that expects raw, uncompressed, newline- or null-terminated JSON.
+type compressionKey struct {
+	disabled   bool
+	streaming  bool
+	streamMode bool
+	kind       CompressionType
+	level      int
+	threshold  int
+	maxSize    int
+}
+
+func (c *Client) compressionKey() compressionKey {
+	return compressionKey{
+		disabled:   c.cfg.DisableCompression,
+		streaming:  c.cfg.StreamingCompression,
+		streamMode: c.streamMode,
+		kind:       c.cfg.CompressionType,
+		level:      c.cfg.CompressionLevel,
+		threshold:  c.cfg.CompressionThreshold,
+		maxSize:    c.cfg.CompressionMaxSize,
+	}
+}
+
+// writeEncoded dispatches an already-JSON-encoded message, applying
+// this client's own compression and chunking, and records the
+// outcome in its Stats.
+func (c *Client) writeEncoded(b []byte) error {
+	if err := c.dispatch(context.Background(), b, &c.Stats); err != nil {
+		c.Stats.Errors.Add(1)
+		return err
+	}
+	c.Stats.Sent.Add(1)
+	return nil
+}
+
+// WriteMessage fills in m's shared fields once, then sends it to
+// every destination, sharing one compression pass across clients
+// configured identically.
+func (mc *MultiClient) WriteMessage(m *Message) error {
+	if len(mc.clients) == 0 {
+		return nil
+	}
+
+	first := mc.clients[0]
+	if m.Host == "" {
+		m.Host = first.host
+	}
+	if m.Timestamp.IsZero() {
+		m.Timestamp = first.clock().Now()
+	}
+	m.ensureExtra()
+
+	groups := make(map[compressionKey][]*Client)
+	for _, c := range mc.clients {
+		groups[c.compressionKey()] = append(groups[c.compressionKey()], c)
+	}
+
+	var firstErr error
+	for key, group := range groups {
+		if err := writeGroupedMessage(group, key, m); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// PingAll probes every destination concurrently, reporting the result
+// keyed by its address, so an operator can tell at startup which of
+// several configured graylog inputs the local send path can actually
+// reach. Each probe is a single throwaway debug-level event; it
+// doesn't count toward that destination's Stats. ctx bounds how long
+// PingAll waits on a single slow destination, not the network write
+// itself, which on a UDP socket essentially never blocks.
+func (mc *MultiClient) PingAll(ctx context.Context) map[net.Addr]error {
+	results := make(map[net.Addr]error, len(mc.clients))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, c := range mc.clients {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			err := c.probe(ctx)
+			mu.Lock()
+			results[c.destAddr()] = err
+			mu.Unlock()
+		}(c)
+	}
+	wg.Wait()
+	return results
+}
+
+// probe sends a single throwaway debug-level event to this client's
+// destination, for MultiClient.PingAll's connectivity check, without
+// affecting Stats. ctx bounds how long the caller waits for the result
+// and is passed through to the send itself, though for a single-chunk
+// probe it rarely matters: a UDP write essentially never blocks on the
+// network the way a TCP handshake would.
+func (c *Client) probe(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		var discard Stats
+		done <- c.writeMessage(ctx, &Message{ShortMessage: "gelf connectivity probe", Level: 7}, &discard)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// writeGroupedMessage marshals m once for group and sends it to every
+// client in group, compressing once and reusing the result when the
+// group's payload doesn't need per-client (streaming) handling.
+func writeGroupedMessage(group []*Client, key compressionKey, m *Message) error {
+	lead := group[0]
+	if lead.cfg.SchemaValidate != nil {
+		if err := lead.cfg.SchemaValidate(*m); err != nil {
+			for _, c := range group {
+				c.Stats.Errors.Add(1)
+			}
+			return err
+		}
+	}
+
+	if err := m.checkRequiredFields(); err != nil {
+		for _, c := range group {
+			c.Stats.Errors.Add(1)
+		}
+		return err
+	}
+
+	b, err := lead.encoder().Encode(m)
+	if err != nil {
+		for _, c := range group {
+			c.Stats.Errors.Add(1)
+		}
+		return err
+	}
+
+	// Streaming compression interleaves compressing and sending, so it
+	// can't share a single precomputed payload. streamMode clients
+	// (NewTCPClient) never compress or chunk at all, so sharing a
+	// compressed payload with them would corrupt their stream; both
+	// fall back to each client doing its own pass. key.streamMode is
+	// already implied by the grouping above -- every member of group
+	// has the same streamMode -- but the check is kept explicit here
+	// rather than relied on silently, since this is exactly the branch
+	// a grouping regression would corrupt.
+	if key.streaming || key.streamMode || len(group) == 1 {
+		var firstErr error
+		for _, c := range group {
+			if err := c.writeEncoded(b); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	payload := b
+	compressed := false
+	if !key.disabled && lead.inCompressionWindow(len(b)) {
+		payload, err = lead.compressAllPooled(b)
+		if err != nil {
+			for _, c := range group {
+				c.Stats.Errors.Add(1)
+			}
+			return err
+		}
+		compressed = true
+	}
+
+	var firstErr error
+	for _, c := range group {
+		c.Stats.UncompressedBytes.Add(uint64(len(b)))
+		if compressed {
+			c.Stats.recordCompressionRatio(len(b), len(payload))
+		}
+		if err := c.sendCounting(context.Background(), c.destAddr(), payload, &c.Stats); err != nil {
+			c.Stats.Errors.Add(1)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		c.Stats.Sent.Add(1)
+	}
+	return firstErr
+}
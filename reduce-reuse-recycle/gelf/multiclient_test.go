@@ -0,0 +1,134 @@
+package gelf
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestMultiClientCompressesPerDestination(t *testing.T) {
+	plainConn := &memConn{}
+	gzipConn := &memConn{}
+	plain := newClient(plainConn, testAddr, Config{DisableCompression: true})
+	gz := newClient(gzipConn, testAddr, Config{})
+	mc := NewMultiClient(plain, gz)
+
+	if err := mc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if len(plainConn.Packets()) != 1 || isGzipped(plainConn.Packets()[0]) {
+		t.Fatalf("expected plain destination to get uncompressed JSON")
+	}
+	if len(gzipConn.Packets()) != 1 || !isGzipped(gzipConn.Packets()[0]) {
+		t.Fatalf("expected gzip destination to get compressed JSON")
+	}
+}
+
+func TestMultiClientSharesCompressionWithinGroup(t *testing.T) {
+	connA := &memConn{}
+	connB := &memConn{}
+	a := newClient(connA, testAddr, Config{})
+	b := newClient(connB, testAddr, Config{})
+	mc := NewMultiClient(a, b)
+
+	if err := mc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if !isGzipped(connA.Packets()[0]) || !isGzipped(connB.Packets()[0]) {
+		t.Fatalf("expected both same-config destinations to receive compressed JSON")
+	}
+}
+
+// TestMultiClientNeverSendsCompressedBytesToTCPDestination guards
+// against a TCP-mode Client being grouped with a same-settings UDP
+// Client under the shared-compression optimization: TCP GELF inputs
+// expect raw, uncompressed, null-terminated JSON, so a compressed
+// payload landing there would be silently dropped or misread.
+func TestMultiClientNeverSendsCompressedBytesToTCPDestination(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		n, _ := conn.Read(buf)
+		received <- buf[:n]
+	}()
+
+	tcpClient, err := NewTCPClient(ln.Addr().String(), Config{})
+	if err != nil {
+		t.Fatalf("NewTCPClient: %v", err)
+	}
+	defer tcpClient.Close()
+
+	udpConn := &memConn{}
+	udpClient := newClient(udpConn, testAddr, Config{})
+
+	mc := NewMultiClient(tcpClient, udpClient)
+	if err := mc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	frame := <-received
+	if len(frame) >= 2 && frame[0] == 0x1f && frame[1] == 0x8b {
+		t.Fatalf("TCP destination received gzip-framed bytes: %x", frame)
+	}
+	if len(udpConn.Packets()) != 1 || !isGzipped(udpConn.Packets()[0]) {
+		t.Fatalf("expected the UDP destination to still get compressed JSON")
+	}
+}
+
+func TestMultiClientSharedCompressionPathRecordsByteStats(t *testing.T) {
+	connA := &memConn{}
+	connB := &memConn{}
+	a := newClient(connA, testAddr, Config{})
+	b := newClient(connB, testAddr, Config{})
+	mc := NewMultiClient(a, b)
+
+	if err := mc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	for name, c := range map[string]*Client{"a": a, "b": b} {
+		if c.Stats.UncompressedBytes.Load() == 0 {
+			t.Fatalf("expected %s.Stats.UncompressedBytes to be recorded via the shared-compression path", name)
+		}
+		if c.Stats.BytesSent.Load() == 0 {
+			t.Fatalf("expected %s.Stats.BytesSent to be recorded via the shared-compression path", name)
+		}
+	}
+}
+
+func TestMultiClientPingAllReportsPerAddressResults(t *testing.T) {
+	okAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 12202}
+	failAddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 12203}
+
+	okConn := &memConn{}
+	failConn := &memConn{}
+	failConn.Close()
+
+	ok := newClient(okConn, okAddr, Config{DisableCompression: true})
+	fail := newClient(failConn, failAddr, Config{DisableCompression: true})
+	mc := NewMultiClient(ok, fail)
+
+	results := mc.PingAll(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if err := results[okAddr]; err != nil {
+		t.Fatalf("got err=%v for reachable destination, want nil", err)
+	}
+	if err := results[failAddr]; err == nil {
+		t.Fatalf("got nil error for unreachable destination, want non-nil")
+	}
+}
@@ -0,0 +1,186 @@
+package gelf
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// compressorPool is what Client.compressors holds: either an
+// unboundedCompressorPool (the default, backed by sync.Pool) or a
+// boundedCompressorPool (Config.MaxPooled), so compressAllPooled and
+// freeCompressor don't need to know which.
+type compressorPool interface {
+	Get() *compressor
+	Put(cz *compressor)
+}
+
+// unboundedCompressorPool is the default compressorPool: a thin
+// wrapper around sync.Pool, which cooperates with the GC by letting
+// pooled items be collected under memory pressure instead of pinning
+// them forever.
+type unboundedCompressorPool struct {
+	pool sync.Pool
+}
+
+func (p *unboundedCompressorPool) Get() *compressor {
+	cz, _ := p.pool.Get().(*compressor)
+	return cz
+}
+
+func (p *unboundedCompressorPool) Put(cz *compressor) {
+	p.pool.Put(cz)
+}
+
+// boundedCompressorPool is Config.MaxPooled's compressorPool: a
+// buffered channel capped at a fixed number of retained compressors,
+// trading sync.Pool's GC cooperation for a predictable memory ceiling.
+// A spike that returns more compressors than the channel can hold
+// simply drops the excess instead of retaining them until the next GC
+// cycle, which for a gzip.Writer plus its buffer can be a meaningful
+// amount of memory per object under an adversarial burst. Get on an
+// empty pool returns nil, same as unboundedCompressorPool, so
+// compressAllPooled's fallback to newCompressor covers both.
+type boundedCompressorPool struct {
+	ch chan *compressor
+}
+
+func newBoundedCompressorPool(max int) *boundedCompressorPool {
+	return &boundedCompressorPool{ch: make(chan *compressor, max)}
+}
+
+func (p *boundedCompressorPool) Get() *compressor {
+	select {
+	case cz := <-p.ch:
+		return cz
+	default:
+		return nil
+	}
+}
+
+func (p *boundedCompressorPool) Put(cz *compressor) {
+	select {
+	case p.ch <- cz:
+	default:
+		// Pool is at Config.MaxPooled capacity; drop cz rather than
+		// growing retained memory past the configured ceiling.
+	}
+}
+
+// compressWriter is the subset of *gzip.Writer (and, as of
+// Config.CompressionType, *zlib.Writer) a pooled compressor needs. See
+// newCompressWriter. Factored out so a test can substitute a
+// fault-injecting fake without touching compressor's own logic, and so
+// a future compression format (zstd, ...) has a seam to implement
+// against instead of compressor being hardcoded to one package.
+type compressWriter interface {
+	io.Writer
+	io.Closer
+	Reset(w io.Writer)
+}
+
+// compressor pairs a compressWriter (a gzip.Writer or zlib.Writer,
+// per Config.CompressionType) with the buffer it writes into, so the
+// pair can be recycled across messages via Client.compressors instead
+// of allocating both afresh on every compressed send.
+type compressor struct {
+	buf *bytes.Buffer
+	zw  compressWriter
+}
+
+// newCompressor builds a compressor for kind at level, propagating
+// newCompressWriter's error for a level it rejects rather than ever
+// returning a *compressor with a nil zw -- a caller is guaranteed
+// either a usable compressor or a clean error, never one that panics
+// on its first Write.
+func newCompressor(kind CompressionType, level int) (*compressor, error) {
+	buf := &bytes.Buffer{}
+	zw, err := newCompressWriter(kind, buf, level)
+	if err != nil {
+		return nil, err
+	}
+	return &compressor{buf: buf, zw: zw}, nil
+}
+
+// safeReset discards whatever cz last held, making it ready to
+// compress a new payload at its original level, and reports whether
+// that succeeded. Today's gzip.Writer.Reset can't fail, but a
+// pluggable compressor swapped in later might -- by recovering from a
+// panic here, a reset that leaves the writer unusable is caught the
+// same way compressAllPooled already catches an abnormal write: the
+// compressor is discarded rather than risking it being handed back out
+// in a broken state.
+func (cz *compressor) safeReset() (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	cz.buf.Reset()
+	cz.zw.Reset(cz.buf)
+	return true
+}
+
+// free returns cz to c.compressors once it's been safely reset for
+// reuse, or discards it if the reset itself failed -- see safeReset.
+// A discarded compressor simply isn't there the next time
+// compressAllPooled calls c.compressors.Get, so it pays for a fresh one
+// instead of reusing a compressor that couldn't be trusted.
+func (c *Client) freeCompressor(cz *compressor) {
+	if cz.safeReset() {
+		c.compressors.Put(cz)
+	}
+}
+
+// compressAllPooled compresses b at the client's configured type and
+// level using a compressor drawn from c.compressors, freeing it back
+// to the pool afterward. Equivalent to compressAll, but avoids
+// allocating a fresh compressWriter and buffer on every call.
+//
+// dirty tracks whether cz is left in a state safe to reuse. It starts
+// true and only flips to false once Write and Close have both
+// completed normally, so if this call exits abnormally -- an error
+// return, or a panic unwinding through the deferred func below -- cz
+// is discarded instead of going back in the pool.
+func (c *Client) compressAllPooled(b []byte) ([]byte, error) {
+	cz := c.compressors.Get()
+	if cz == nil {
+		var err error
+		cz, err = newCompressor(c.compressionType(), c.compressionLevel())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dirty := true
+	defer func() {
+		if !dirty {
+			c.freeCompressor(cz)
+		}
+	}()
+
+	if _, err := cz.zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := cz.zw.Close(); err != nil {
+		return nil, err
+	}
+	dirty = false
+
+	out := make([]byte, cz.buf.Len())
+	copy(out, cz.buf.Bytes())
+	return out, nil
+}
+
+// Warm pre-allocates n compressors and seeds the pool compressAllPooled
+// draws from, so a startup burst of messages doesn't pay the cost of
+// allocating a compressWriter and buffer on its first n sends.
+func (c *Client) Warm(n int) {
+	for i := 0; i < n; i++ {
+		cz, err := newCompressor(c.compressionType(), c.compressionLevel())
+		if err != nil {
+			return
+		}
+		c.compressors.Put(cz)
+	}
+}
@@ -0,0 +1,192 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestClientWarmPopulatesUsableCompressors(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+	c.Warm(4)
+
+	payload := []byte("warmed compressor output should still decompress correctly")
+	cb, err := c.compressAllPooled(payload)
+	if err != nil {
+		t.Fatalf("compressAllPooled: %v", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(cb))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer zr.Close()
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("got %q, want %q", got, payload)
+	}
+}
+
+// panickyResetWriter is a compressWriter fake whose Reset panics,
+// simulating a pluggable compressor that leaves itself unusable on
+// reset. Write and Close behave normally, passing through to w.
+type panickyResetWriter struct {
+	w io.Writer
+}
+
+func (p *panickyResetWriter) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p *panickyResetWriter) Close() error                { return nil }
+func (p *panickyResetWriter) Reset(io.Writer)             { panic("simulated reset failure") }
+
+func TestFreeCompressorDiscardsOneWhoseResetFails(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+
+	buf := &bytes.Buffer{}
+	cz := &compressor{buf: buf, zw: &panickyResetWriter{w: buf}}
+	c.compressors.Put(cz)
+
+	if _, err := c.compressAllPooled([]byte("first payload, using the faulty compressor")); err != nil {
+		t.Fatalf("compressAllPooled: %v", err)
+	}
+
+	payload := []byte("second payload, must use a freshly allocated compressor")
+	cb, err := c.compressAllPooled(payload)
+	if err != nil {
+		t.Fatalf("compressAllPooled: %v", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(cb))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer zr.Close()
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("got %q, want %q", got, payload)
+	}
+}
+
+func TestCompressAllPooledDiscardsCompressorAfterPanickedWrite(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+
+	// Drawing a compressor the same way compressAllPooled would, then
+	// panicking before it would otherwise be returned to the pool,
+	// simulates a panic during send (e.g. from a custom conn's
+	// WriteTo) interrupting a checked-out compressor mid-operation.
+	// sync.Pool gives no guarantee an item survives to a later Get
+	// even under normal use, so this test doesn't assert anything
+	// about pool contents -- only that a subsequent compressAllPooled
+	// call still produces valid output regardless.
+	func() {
+		defer func() { recover() }()
+
+		cz, err := newCompressor(c.compressionType(), c.compressionLevel())
+		if err != nil {
+			t.Fatalf("newCompressor: %v", err)
+		}
+		if _, err := cz.zw.Write([]byte("partial write before the panic")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		panic("simulated abnormal exit mid-write")
+	}()
+
+	payload := []byte("next message after the panic")
+	cb, err := c.compressAllPooled(payload)
+	if err != nil {
+		t.Fatalf("compressAllPooled: %v", err)
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(cb))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	defer zr.Close()
+	got, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("got %q, want %q", got, payload)
+	}
+}
+
+func TestMaxPooledCapsRetainedCompressors(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{MaxPooled: 2})
+
+	for i := 0; i < 5; i++ {
+		cz, err := newCompressor(c.compressionType(), c.compressionLevel())
+		if err != nil {
+			t.Fatalf("newCompressor: %v", err)
+		}
+		c.compressors.Put(cz)
+	}
+
+	retained := 0
+	for c.compressors.Get() != nil {
+		retained++
+	}
+	if retained != 2 {
+		t.Fatalf("got %d retained compressors, want 2 (Config.MaxPooled)", retained)
+	}
+}
+
+// TestNewCompressorRejectsInvalidLevelCleanly guards the invariant
+// newCompressor's doc comment relies on: a level neither
+// newCompressWriter accepts fails construction with a plain error and
+// never hands back a *compressor with a nil zw for a caller to panic
+// on at the first Write. Checked for both CompressionGzip and
+// CompressionZlib, since both go through the same constructor.
+func TestNewCompressorRejectsInvalidLevelCleanly(t *testing.T) {
+	for _, kind := range []CompressionType{CompressionGzip, CompressionZlib} {
+		cz, err := newCompressor(kind, 99)
+		if err == nil {
+			t.Fatalf("kind=%v: got nil error for an out-of-range level, want an error", kind)
+		}
+		if cz != nil {
+			t.Fatalf("kind=%v: got a non-nil *compressor alongside an error, want nil", kind)
+		}
+	}
+}
+
+// TestCompressAllPooledSurfacesInvalidLevelAsErrorNotPanic is the
+// same guarantee exercised through compressAllPooled, the pooled path
+// a real compressed send takes: with an unusable CompressionLevel it
+// returns an error rather than panicking at cz.zw.Write.
+func TestCompressAllPooledSurfacesInvalidLevelAsErrorNotPanic(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{CompressionLevel: 99})
+
+	if _, err := c.compressAllPooled([]byte("payload")); err == nil {
+		t.Fatalf("got nil error, want one for CompressionLevel 99")
+	}
+}
+
+func TestCompressAllPooledReusesCompressorAcrossCalls(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+
+	first, err := c.compressAllPooled([]byte("first payload"))
+	if err != nil {
+		t.Fatalf("compressAllPooled: %v", err)
+	}
+	second, err := c.compressAllPooled([]byte("a completely different second payload"))
+	if err != nil {
+		t.Fatalf("compressAllPooled: %v", err)
+	}
+
+	for name, cb := range map[string][]byte{"first": first, "second": second} {
+		zr, err := gzip.NewReader(bytes.NewReader(cb))
+		if err != nil {
+			t.Fatalf("%s: gzip.NewReader: %v", name, err)
+		}
+		if _, err := io.ReadAll(zr); err != nil {
+			t.Fatalf("%s: ReadAll: %v", name, err)
+		}
+		zr.Close()
+	}
+}
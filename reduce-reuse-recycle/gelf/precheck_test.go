@@ -0,0 +1,57 @@
+package gelf
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAsyncClientPrecheckMessageSizeRejectsOversizedMessageSynchronously(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression:  true,
+		PrecheckMessageSize: true,
+	})
+	ac := NewAsyncClient(c, 4)
+	defer ac.Close()
+
+	huge := strings.Repeat("x", defaultMaxChunkSize*(maxChunks+1))
+	err := ac.WriteMessage(&Message{ShortMessage: huge})
+
+	var tooLarge *ErrMessageTooLarge
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("got %v, want *ErrMessageTooLarge", err)
+	}
+	if ac.QueueLen() != 0 {
+		t.Fatalf("QueueLen() = %d, want 0 -- rejected message must not be enqueued", ac.QueueLen())
+	}
+}
+
+func TestAsyncClientWithoutPrecheckEnqueuesOversizedMessage(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+	ac := NewAsyncClient(c, 4)
+
+	huge := strings.Repeat("x", defaultMaxChunkSize*(maxChunks+1))
+	if err := ac.WriteMessage(&Message{ShortMessage: huge}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := ac.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func TestPrecheckCompressionAcceptsMessageThatFitsOnceCompressed(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		PrecheckMessageSize: true,
+		PrecheckCompression: true,
+	})
+	ac := NewAsyncClient(c, 4)
+	defer ac.Close()
+
+	repetitive := strings.Repeat("a", defaultMaxChunkSize*(maxChunks+1))
+	if err := ac.WriteMessage(&Message{ShortMessage: repetitive}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package gelf
+
+import "sync"
+
+// priorityQueue buckets queuedMessages by GELF level (lower level
+// number means higher severity) so AsyncClient can always dequeue the
+// most severe message waiting, rather than strict FIFO order. It's
+// bounded by a total message count across all levels; pushing past
+// that bound evicts the single least severe message currently queued
+// in favor of the new one, or, if nothing less severe is queued,
+// rejects the new message instead.
+type priorityQueue struct {
+	mu       sync.Mutex
+	byLevel  map[int32][]queuedMessage
+	count    int
+	bytes    int64
+	capacity int
+}
+
+func newPriorityQueue(capacity int) *priorityQueue {
+	return &priorityQueue{
+		byLevel:  make(map[int32][]queuedMessage),
+		capacity: capacity,
+	}
+}
+
+// push adds qm at level. It reports whether qm was enqueued, and, if
+// making room for it required evicting a lower-severity message,
+// returns that message so the caller can account for the drop.
+func (pq *priorityQueue) push(level int32, qm queuedMessage) (pushed bool, evicted *queuedMessage) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.count >= pq.capacity {
+		worstLevel, ok := pq.worstLevelLocked()
+		if !ok || worstLevel <= level {
+			// Nothing queued is less severe than the new message, or
+			// the queue is (somehow) empty despite count >= capacity;
+			// either way, the new message loses out.
+			return false, nil
+		}
+		bucket := pq.byLevel[worstLevel]
+		ev := bucket[0]
+		if len(bucket) == 1 {
+			delete(pq.byLevel, worstLevel)
+		} else {
+			pq.byLevel[worstLevel] = bucket[1:]
+		}
+		pq.count--
+		pq.bytes -= int64(ev.size)
+		evicted = &ev
+	}
+
+	pq.byLevel[level] = append(pq.byLevel[level], qm)
+	pq.count++
+	pq.bytes += int64(qm.size)
+	return true, evicted
+}
+
+// pop removes and returns the queued message at the most severe
+// (numerically lowest) level present, in FIFO order within that level.
+func (pq *priorityQueue) pop() (queuedMessage, bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	bestLevel, ok := pq.bestLevelLocked()
+	if !ok {
+		return queuedMessage{}, false
+	}
+	bucket := pq.byLevel[bestLevel]
+	qm := bucket[0]
+	if len(bucket) == 1 {
+		delete(pq.byLevel, bestLevel)
+	} else {
+		pq.byLevel[bestLevel] = bucket[1:]
+	}
+	pq.count--
+	pq.bytes -= int64(qm.size)
+	return qm, true
+}
+
+func (pq *priorityQueue) len() int {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	return pq.count
+}
+
+func (pq *priorityQueue) queuedBytes() int64 {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	return pq.bytes
+}
+
+func (pq *priorityQueue) bestLevelLocked() (int32, bool) {
+	var best int32
+	found := false
+	for level, bucket := range pq.byLevel {
+		if len(bucket) == 0 {
+			continue
+		}
+		if !found || level < best {
+			best, found = level, true
+		}
+	}
+	return best, found
+}
+
+func (pq *priorityQueue) worstLevelLocked() (int32, bool) {
+	var worst int32
+	found := false
+	for level, bucket := range pq.byLevel {
+		if len(bucket) == 0 {
+			continue
+		}
+		if !found || level > worst {
+			worst, found = level, true
+		}
+	}
+	return worst, found
+}
@@ -0,0 +1,99 @@
+package gelf
+
+import (
+	"sync"
+)
+
+// QuotaClient wraps a Client to enforce Config.QuotaBytes over a
+// rolling Config.QuotaWindow: once that many encoded bytes have been
+// sent within the current window, further messages are dropped
+// (Stats.Dropped) until the window rolls over, rather than sent
+// regardless of volume. Unlike Config.SampleRates' probabilistic
+// thinning, this is a hard cutoff sized in bytes, not a sustained
+// rate -- a burst that exhausts the quota in the first second of an
+// hour-long window still waits out the rest of the hour.
+type QuotaClient struct {
+	c *Client
+
+	mu          sync.Mutex
+	haveWindow  bool
+	windowStart int64 // UnixNano, valid only if haveWindow
+	used        int64
+}
+
+// NewQuotaClient wraps c, enforcing c's Config.QuotaBytes over
+// Config.QuotaWindow. A zero QuotaBytes disables enforcement: every
+// message is sent exactly as Client.WriteMessage would send it.
+func NewQuotaClient(c *Client) *QuotaClient {
+	return &QuotaClient{c: c}
+}
+
+// WriteMessage encodes m to measure its size against the quota, then,
+// if the window has room, sends it via the wrapped Client exactly as
+// Client.WriteMessage would. If the quota is exhausted for the current
+// window, m is dropped (Stats.Dropped) and WriteMessage returns nil,
+// matching AsyncClient's drop-without-error convention for messages
+// that don't make it out by policy rather than by failure.
+func (qc *QuotaClient) WriteMessage(m *Message) error {
+	if qc.c.cfg.QuotaBytes <= 0 {
+		return qc.c.WriteMessage(m)
+	}
+
+	b, err := qc.c.encoder().Encode(m)
+	if err != nil {
+		qc.c.Stats.Errors.Add(1)
+		return err
+	}
+
+	if !qc.reserve(int64(len(b))) {
+		qc.c.Stats.Dropped.Add(1)
+		qc.c.cfg.notifyDrop(DropReasonQuota, m)
+		return nil
+	}
+	return qc.c.WriteMessage(m)
+}
+
+// reserve reports whether size bytes fit in the current window's
+// remaining quota, rolling the window over first if it has expired,
+// and charging size against it if so.
+func (qc *QuotaClient) reserve(size int64) bool {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+
+	now := qc.c.clock().Now().UnixNano()
+	if !qc.haveWindow || now-qc.windowStart >= int64(qc.c.cfg.QuotaWindow) {
+		qc.haveWindow = true
+		qc.windowStart = now
+		qc.used = 0
+	}
+
+	if qc.used+size > qc.c.cfg.QuotaBytes {
+		return false
+	}
+	qc.used += size
+	return true
+}
+
+// Remaining returns how many bytes may still be sent in the current
+// window before QuotaClient starts dropping messages. It does not
+// itself roll the window over; that happens lazily on the next
+// WriteMessage.
+func (qc *QuotaClient) Remaining() int64 {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+
+	now := qc.c.clock().Now().UnixNano()
+	if !qc.haveWindow || now-qc.windowStart >= int64(qc.c.cfg.QuotaWindow) {
+		return qc.c.cfg.QuotaBytes
+	}
+	remaining := qc.c.cfg.QuotaBytes - qc.used
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Close closes the wrapped Client.
+func (qc *QuotaClient) Close() error {
+	return qc.c.Close()
+}
@@ -0,0 +1,68 @@
+package gelf
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuotaClientDropsOnceExhausted(t *testing.T) {
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		QuotaBytes:         200,
+		QuotaWindow:        time.Minute,
+		Clock:              clk,
+	})
+	qc := NewQuotaClient(c)
+
+	for i := 0; i < 10; i++ {
+		if err := qc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+			t.Fatalf("WriteMessage %d: %v", i, err)
+		}
+	}
+
+	sent := len(conn.Packets())
+	if sent == 0 || sent >= 10 {
+		t.Fatalf("got %d packets sent, want somewhere between 1 and 9 before the quota ran out", sent)
+	}
+	if got := c.Stats.Dropped.Load(); got == 0 {
+		t.Fatalf("expected some messages dropped once the quota was exhausted")
+	}
+	if got := qc.Remaining(); got >= 90 {
+		t.Fatalf("got Remaining()=%d, want less than a message's worth", got)
+	}
+}
+
+func TestQuotaClientWindowRolloverRestoresSending(t *testing.T) {
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		QuotaBytes:         200,
+		QuotaWindow:        time.Minute,
+		Clock:              clk,
+	})
+	qc := NewQuotaClient(c)
+
+	for i := 0; i < 10; i++ {
+		if err := qc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+			t.Fatalf("WriteMessage %d: %v", i, err)
+		}
+	}
+	exhausted := len(conn.Packets())
+	if got := qc.Remaining(); got >= 90 {
+		t.Fatalf("got Remaining()=%d before rollover, want less than a message's worth", got)
+	}
+
+	clk.Advance(time.Minute)
+	if err := qc.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage after rollover: %v", err)
+	}
+	if got := len(conn.Packets()); got != exhausted+1 {
+		t.Fatalf("got %d packets after rollover, want %d", got, exhausted+1)
+	}
+	if got := qc.Remaining(); got <= 0 {
+		t.Fatalf("got Remaining()=%d after rollover, want > 0", got)
+	}
+}
@@ -0,0 +1,247 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// IncompleteMessage describes a chunk group that ReassembleAll could not
+// fully reassemble because one or more of its declared chunks never
+// showed up in the capture.
+type IncompleteMessage struct {
+	ID               [8]byte
+	ExpectedCount    int
+	MissingSequences []int
+}
+
+// chunkEntry is one decoded chunk, pending reassembly into its group.
+type chunkEntry struct {
+	seq     int
+	count   int
+	payload []byte
+}
+
+// ReassembleChunks reassembles the GELF chunks of a single message,
+// given in any order, into the original decompressed payload. Packets
+// without the GELF chunk magic are treated as already-complete,
+// unchunked messages and simply decompressed as-is; ReassembleChunks
+// accepts exactly one packet in that case.
+func ReassembleChunks(packets [][]byte) ([]byte, error) {
+	if len(packets) == 1 && !isChunkPacket(packets[0]) {
+		return decompressPayload(packets[0])
+	}
+
+	entries := make([]chunkEntry, 0, len(packets))
+	var id [8]byte
+	haveID := false
+	for _, p := range packets {
+		e, pid, err := parseChunkPacket(p)
+		if err != nil {
+			return nil, err
+		}
+		if haveID && pid != id {
+			return nil, fmt.Errorf("gelf: packets belong to different chunk groups (%x and %x)", id, pid)
+		}
+		id, haveID = pid, true
+		entries = append(entries, e)
+	}
+
+	payload, missing, count := reassembleEntries(entries)
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("gelf: chunk group %x is missing sequences %v of %d", id, missing, count)
+	}
+	return decompressPayload(payload)
+}
+
+// ReassembleChunksStrict is ReassembleChunks with every assumption
+// about the input verified rather than tolerated, for validating that
+// a capture of one message's chunks is intact before trusting it: it
+// checks each packet's chunk magic, that every chunk declares the same
+// count, that sequence numbers are unique and within [0,count), and
+// that exactly count chunks were given, before attempting
+// decompression. Any violation returns a descriptive error naming the
+// offending chunk by its index in packets.
+func ReassembleChunksStrict(packets [][]byte) ([]byte, error) {
+	if len(packets) == 0 {
+		return nil, fmt.Errorf("gelf: no packets given")
+	}
+	if len(packets) == 1 && !isChunkPacket(packets[0]) {
+		return decompressPayload(packets[0])
+	}
+
+	var id [8]byte
+	var count int
+	seen := make(map[int]bool, len(packets))
+	entries := make([]chunkEntry, 0, len(packets))
+
+	for i, p := range packets {
+		if !isChunkPacket(p) {
+			return nil, fmt.Errorf("gelf: packet %d is missing the chunk magic bytes", i)
+		}
+		e, pid, err := parseChunkPacket(p)
+		if err != nil {
+			return nil, fmt.Errorf("gelf: packet %d: %w", i, err)
+		}
+		if i == 0 {
+			id, count = pid, e.count
+		} else if pid != id {
+			return nil, fmt.Errorf("gelf: packet %d belongs to chunk group %x, want %x", i, pid, id)
+		} else if e.count != count {
+			return nil, fmt.Errorf("gelf: packet %d declares count %d, want %d", i, e.count, count)
+		}
+		if e.seq < 0 || e.seq >= count {
+			return nil, fmt.Errorf("gelf: packet %d has sequence %d, outside [0,%d)", i, e.seq, count)
+		}
+		if seen[e.seq] {
+			return nil, fmt.Errorf("gelf: packet %d duplicates sequence %d", i, e.seq)
+		}
+		seen[e.seq] = true
+		entries = append(entries, e)
+	}
+
+	if len(entries) != count {
+		return nil, fmt.Errorf("gelf: chunk group %x has %d chunks, declared count is %d", id, len(entries), count)
+	}
+
+	payload, _, _ := reassembleEntries(entries)
+	return decompressPayload(payload)
+}
+
+// ReassembleAll groups a capture of GELF packets by chunk-group ID,
+// reassembling and decompressing every message whose chunks are all
+// present, and reporting the rest as IncompleteMessage. Packets without
+// the chunk magic are treated as already-complete, unchunked messages.
+// complete holds unchunked messages in capture order, followed by
+// reassembled chunk groups in the order their first chunk appeared.
+func ReassembleAll(packets [][]byte) (complete [][]byte, incomplete []IncompleteMessage, err error) {
+	groups := make(map[[8]byte][]chunkEntry)
+	var order [][8]byte
+	seen := make(map[[8]byte]bool)
+
+	for _, p := range packets {
+		if !isChunkPacket(p) {
+			b, err := decompressPayload(p)
+			if err != nil {
+				return nil, nil, err
+			}
+			complete = append(complete, b)
+			continue
+		}
+
+		e, id, err := parseChunkPacket(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !seen[id] {
+			seen[id] = true
+			order = append(order, id)
+		}
+		groups[id] = append(groups[id], e)
+	}
+
+	for _, id := range order {
+		payload, missing, count := reassembleEntries(groups[id])
+		if len(missing) > 0 {
+			incomplete = append(incomplete, IncompleteMessage{
+				ID:               id,
+				ExpectedCount:    count,
+				MissingSequences: missing,
+			})
+			continue
+		}
+		b, err := decompressPayload(payload)
+		if err != nil {
+			return nil, nil, err
+		}
+		complete = append(complete, b)
+	}
+
+	return complete, incomplete, nil
+}
+
+// reassembleEntries orders entries by sequence and concatenates their
+// payloads, reporting any sequence numbers in [0,count) that weren't
+// present. count is taken from the entries themselves, so a group with
+// inconsistent declared counts is treated as missing every sequence its
+// largest-declared count implies but doesn't have.
+func reassembleEntries(entries []chunkEntry) (payload []byte, missing []int, count int) {
+	for _, e := range entries {
+		if e.count > count {
+			count = e.count
+		}
+	}
+
+	have := make(map[int][]byte, len(entries))
+	for _, e := range entries {
+		have[e.seq] = e.payload
+	}
+
+	for seq := 0; seq < count; seq++ {
+		p, ok := have[seq]
+		if !ok {
+			missing = append(missing, seq)
+			continue
+		}
+		payload = append(payload, p...)
+	}
+	sort.Ints(missing)
+	return payload, missing, count
+}
+
+func isChunkPacket(p []byte) bool {
+	return len(p) >= chunkHeaderLen && p[0] == chunkMagic0 && p[1] == chunkMagic1
+}
+
+func parseChunkPacket(p []byte) (chunkEntry, [8]byte, error) {
+	var id [8]byte
+	if !isChunkPacket(p) {
+		return chunkEntry{}, id, fmt.Errorf("gelf: packet missing chunk magic")
+	}
+	copy(id[:], p[2:10])
+	return chunkEntry{
+		seq:     int(p[10]),
+		count:   int(p[11]),
+		payload: p[chunkHeaderLen:],
+	}, id, nil
+}
+
+// decompressPayload gunzips or un-zlibs b if it looks compressed,
+// otherwise returns it unchanged: GELF messages may be sent
+// uncompressed, gzipped, or zlib-compressed (see Config.CompressionType),
+// and a reassembler working from a raw capture has no other signal to
+// go on than the magic bytes each format starts with.
+func decompressPayload(b []byte) ([]byte, error) {
+	switch {
+	case len(b) >= 2 && b[0] == 0x1f && b[1] == 0x8b:
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	case looksZlib(b):
+		zr, err := zlib.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	default:
+		return b, nil
+	}
+}
+
+// looksZlib reports whether b starts with a valid zlib (RFC 1950)
+// header: a CM/CINFO byte of 0x78 (deflate, 32K window) followed by a
+// FLG byte that makes the big-endian uint16 of the two a multiple of
+// 31, the checksum the format's header is defined to satisfy.
+func looksZlib(b []byte) bool {
+	if len(b) < 2 || b[0] != 0x78 {
+		return false
+	}
+	return (uint16(b[0])<<8|uint16(b[1]))%31 == 0
+}
@@ -0,0 +1,159 @@
+package gelf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func chunkFor(id [8]byte, seq, count int, payload []byte) []byte {
+	p := make([]byte, chunkHeaderLen+len(payload))
+	p[0], p[1] = chunkMagic0, chunkMagic1
+	copy(p[2:10], id[:])
+	p[10] = byte(seq)
+	p[11] = byte(count)
+	copy(p[chunkHeaderLen:], payload)
+	return p
+}
+
+func TestReassembleAllSeparatesCompleteFromIncomplete(t *testing.T) {
+	complete := [8]byte{1, 1, 1, 1, 1, 1, 1, 1}
+	broken := [8]byte{2, 2, 2, 2, 2, 2, 2, 2}
+
+	packets := [][]byte{
+		chunkFor(complete, 1, 2, []byte("world")),
+		chunkFor(broken, 0, 3, []byte("a")),
+		[]byte(`{"short_message":"unchunked"}`),
+		chunkFor(complete, 0, 2, []byte("hello ")),
+		chunkFor(broken, 2, 3, []byte("c")),
+	}
+
+	gotComplete, incomplete, err := ReassembleAll(packets)
+	if err != nil {
+		t.Fatalf("ReassembleAll: %v", err)
+	}
+
+	wantComplete := [][]byte{
+		[]byte(`{"short_message":"unchunked"}`),
+		[]byte("hello world"),
+	}
+	if !reflect.DeepEqual(gotComplete, wantComplete) {
+		t.Fatalf("got complete %q, want %q", gotComplete, wantComplete)
+	}
+
+	if len(incomplete) != 1 {
+		t.Fatalf("got %d incomplete messages, want 1", len(incomplete))
+	}
+	im := incomplete[0]
+	if im.ID != broken {
+		t.Fatalf("got incomplete ID %x, want %x", im.ID, broken)
+	}
+	if im.ExpectedCount != 3 {
+		t.Fatalf("got ExpectedCount=%d, want 3", im.ExpectedCount)
+	}
+	if !reflect.DeepEqual(im.MissingSequences, []int{1}) {
+		t.Fatalf("got MissingSequences=%v, want [1]", im.MissingSequences)
+	}
+}
+
+func TestReassembleChunksOrdersOutOfSequenceInput(t *testing.T) {
+	id := [8]byte{9, 9, 9, 9, 9, 9, 9, 9}
+	packets := [][]byte{
+		chunkFor(id, 2, 3, []byte("!")),
+		chunkFor(id, 0, 3, []byte("foo")),
+		chunkFor(id, 1, 3, []byte("bar")),
+	}
+
+	got, err := ReassembleChunks(packets)
+	if err != nil {
+		t.Fatalf("ReassembleChunks: %v", err)
+	}
+	if string(got) != "foobar!" {
+		t.Fatalf("got %q, want %q", got, "foobar!")
+	}
+}
+
+func TestReassembleChunksReportsMissingSequence(t *testing.T) {
+	id := [8]byte{3, 3, 3, 3, 3, 3, 3, 3}
+	packets := [][]byte{
+		chunkFor(id, 0, 2, []byte("only")),
+	}
+
+	if _, err := ReassembleChunks(packets); err == nil {
+		t.Fatalf("expected an error for a chunk group missing sequence 1")
+	}
+}
+
+func TestReassembleChunksStrictAcceptsAnIntactGroup(t *testing.T) {
+	id := [8]byte{7, 7, 7, 7, 7, 7, 7, 7}
+	packets := [][]byte{
+		chunkFor(id, 0, 2, []byte("foo")),
+		chunkFor(id, 1, 2, []byte("bar")),
+	}
+	got, err := ReassembleChunksStrict(packets)
+	if err != nil {
+		t.Fatalf("ReassembleChunksStrict: %v", err)
+	}
+	if string(got) != "foobar" {
+		t.Fatalf("got %q, want %q", got, "foobar")
+	}
+}
+
+func TestReassembleChunksStrictRejectsViolations(t *testing.T) {
+	id := [8]byte{8, 8, 8, 8, 8, 8, 8, 8}
+	other := [8]byte{9, 9, 9, 9, 9, 9, 9, 9}
+
+	tests := []struct {
+		name    string
+		packets [][]byte
+	}{
+		{
+			name: "missing magic",
+			packets: [][]byte{
+				chunkFor(id, 0, 2, []byte("a")),
+				[]byte("not a chunk at all"),
+			},
+		},
+		{
+			name: "mismatched id",
+			packets: [][]byte{
+				chunkFor(id, 0, 2, []byte("a")),
+				chunkFor(other, 1, 2, []byte("b")),
+			},
+		},
+		{
+			name: "inconsistent count",
+			packets: [][]byte{
+				chunkFor(id, 0, 2, []byte("a")),
+				chunkFor(id, 1, 3, []byte("b")),
+			},
+		},
+		{
+			name: "sequence out of range",
+			packets: [][]byte{
+				chunkFor(id, 0, 2, []byte("a")),
+				chunkFor(id, 2, 2, []byte("b")),
+			},
+		},
+		{
+			name: "duplicate sequence",
+			packets: [][]byte{
+				chunkFor(id, 0, 2, []byte("a")),
+				chunkFor(id, 0, 2, []byte("b")),
+			},
+		},
+		{
+			name: "fewer chunks than declared",
+			packets: [][]byte{
+				chunkFor(id, 0, 3, []byte("a")),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReassembleChunksStrict(tt.packets); err == nil {
+				t.Fatalf("expected an error for %s", tt.name)
+			}
+		})
+	}
+}
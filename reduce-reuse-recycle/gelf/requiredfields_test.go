@@ -0,0 +1,52 @@
+package gelf
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWriteMessageRejectsEmptyShortMessage(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	err := c.WriteMessage(&Message{})
+	var missing *ErrMissingRequiredField
+	if !errors.As(err, &missing) {
+		t.Fatalf("got %v, want *ErrMissingRequiredField", err)
+	}
+	if missing.Field != "short_message" {
+		t.Fatalf("got Field=%q, want %q", missing.Field, "short_message")
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("got %d packets sent, want 0 for a message missing short_message", len(conn.Packets()))
+	}
+}
+
+func TestWriteMessageRejectsEmptyHostFromHostFunc(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		HostFunc:           func() string { return "" },
+	})
+
+	err := c.WriteMessage(&Message{ShortMessage: "hi"})
+	var missing *ErrMissingRequiredField
+	if !errors.As(err, &missing) {
+		t.Fatalf("got %v, want *ErrMissingRequiredField", err)
+	}
+	if missing.Field != "host" {
+		t.Fatalf("got Field=%q, want %q", missing.Field, "host")
+	}
+}
+
+func TestWriteMessageAcceptsExplicitHostAndShortMessage(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{Host: "worker-1", ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if len(conn.Packets()) != 1 {
+		t.Fatalf("got %d packets, want 1", len(conn.Packets()))
+	}
+}
@@ -0,0 +1,81 @@
+package gelf
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// isTemporaryError reports whether err looks transient enough to be
+// worth retrying -- a network-level timeout/temporary condition, or a
+// single chunk failing to write as a complete datagram. Anything
+// else (schema validation failures, encoding errors, a closed
+// connection) is treated as permanent: retrying it would just fail
+// again the same way.
+func isTemporaryError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout() || netErr.Temporary() //nolint:staticcheck // Temporary is deprecated but still the right signal here
+	}
+	var cwErr *ChunkWriteError
+	return errors.As(err, &cwErr)
+}
+
+// retryItem is a message that failed with a temporary error and is
+// waiting for its next attempt.
+type retryItem struct {
+	m           *Message
+	attempt     int
+	nextAttempt time.Time
+}
+
+// retryQueue holds messages awaiting a retry, bounded to cap entries
+// so a prolonged outage can't grow it without limit; once full, a
+// newly failed message is dropped instead of queued.
+type retryQueue struct {
+	mu    sync.Mutex
+	items []retryItem
+	cap   int
+}
+
+func newRetryQueue(cap int) *retryQueue {
+	return &retryQueue{cap: cap}
+}
+
+// push adds it, reporting whether there was room for it.
+func (rq *retryQueue) push(it retryItem) bool {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	if len(rq.items) >= rq.cap {
+		return false
+	}
+	rq.items = append(rq.items, it)
+	return true
+}
+
+// due removes and returns every item whose nextAttempt has passed.
+func (rq *retryQueue) due(now time.Time) []retryItem {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	var ready []retryItem
+	remaining := rq.items[:0]
+	for _, it := range rq.items {
+		if !now.Before(it.nextAttempt) {
+			ready = append(ready, it)
+		} else {
+			remaining = append(remaining, it)
+		}
+	}
+	rq.items = remaining
+	return ready
+}
+
+func (rq *retryQueue) len() int {
+	rq.mu.Lock()
+	defer rq.mu.Unlock()
+	return len(rq.items)
+}
@@ -0,0 +1,46 @@
+package gelf
+
+import "testing"
+
+func TestSampleRatesDropBelowThreshold(t *testing.T) {
+	conn := &memConn{}
+	calls := []float64{0.05, 0.5, 0.95}
+	i := 0
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		SampleRates:        map[int]float64{7: 0.1},
+		Rand: func() float64 {
+			v := calls[i%len(calls)]
+			i++
+			return v
+		},
+	})
+
+	for j := 0; j < 3; j++ {
+		if err := c.WriteMessage(&Message{ShortMessage: "debug", Level: 7}); err != nil {
+			t.Fatalf("WriteMessage: %v", err)
+		}
+	}
+
+	if got := len(conn.Packets()); got != 1 {
+		t.Fatalf("got %d packets sent, want 1 (only the 0.05 draw beats a 0.1 rate)", got)
+	}
+	if got := c.Stats.Dropped.Load(); got != 2 {
+		t.Fatalf("got Dropped=%d, want 2", got)
+	}
+}
+
+func TestSampleRatesUnlistedLevelAlwaysSends(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		SampleRates:        map[int]float64{7: 0.0},
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "error", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if got := len(conn.Packets()); got != 1 {
+		t.Fatalf("expected the unsampled level to send, got %d packets", got)
+	}
+}
@@ -0,0 +1,16 @@
+package gelf
+
+import "fmt"
+
+// RequireFields returns a Config.SchemaValidate function that rejects
+// any Message missing one of the given Extra keys.
+func RequireFields(keys ...string) func(Message) error {
+	return func(m Message) error {
+		for _, k := range keys {
+			if _, ok := m.Extra[k]; !ok {
+				return fmt.Errorf("gelf: message missing required field %q", k)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,26 @@
+package gelf
+
+import "testing"
+
+func TestSchemaValidateRejectsBeforeSending(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		SchemaValidate:     RequireFields("service"),
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "missing field"}); err == nil {
+		t.Fatalf("expected a validation error")
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("expected nothing sent for an invalid message")
+	}
+
+	ok := &Message{ShortMessage: "has field", Extra: map[string]interface{}{"service": "api"}}
+	if err := c.WriteMessage(ok); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if len(conn.Packets()) != 1 {
+		t.Fatalf("expected the valid message to be sent")
+	}
+}
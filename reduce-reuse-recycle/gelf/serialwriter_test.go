@@ -0,0 +1,222 @@
+package gelf
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// overlapDetector records whether two Write calls were ever in flight
+// at the same time, to catch interleaving that a plain mutex-free
+// io.Writer shared by several bufio.Writer workers would allow.
+type overlapDetector struct {
+	mu       sync.Mutex
+	inFlight bool
+	overlap  bool
+}
+
+func (d *overlapDetector) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	if d.inFlight {
+		d.overlap = true
+	}
+	d.inFlight = true
+	d.mu.Unlock()
+
+	time.Sleep(time.Millisecond)
+
+	d.mu.Lock()
+	d.inFlight = false
+	d.mu.Unlock()
+	return len(p), nil
+}
+
+func TestSerializingWriterNeverInterleavesPhysicalWrites(t *testing.T) {
+	const capacity = 4
+	det := &overlapDetector{}
+	w := NewSerializingWriter(capacity, det)
+	defer w.Close()
+
+	large := make([]byte, 32*1024)
+	var wg sync.WaitGroup
+	for i := 0; i < capacity; i++ {
+		wg.Add(1)
+		go func(b byte) {
+			defer wg.Done()
+			p := make([]byte, len(large))
+			for i := range p {
+				p[i] = b
+			}
+			if _, err := w.Write(p); err != nil {
+				t.Errorf("Write: %v", err)
+			}
+		}(byte(i + 1))
+	}
+	wg.Wait()
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	det.mu.Lock()
+	overlapped := det.overlap
+	det.mu.Unlock()
+	if overlapped {
+		t.Fatalf("physical writes to the underlying writer overlapped")
+	}
+}
+
+func TestAutoAppendNewlineWriterAppendsWhenMissing(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewAutoAppendNewlineWriter(1, &buf)
+	defer w.Close()
+
+	if _, err := w.Write([]byte("no newline here")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // let the worker dequeue before Flush races it
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := buf.String(); got != "no newline here\n" {
+		t.Fatalf("got %q, want a trailing newline appended", got)
+	}
+}
+
+func TestAutoAppendNewlineWriterLeavesExistingNewlineAlone(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewAutoAppendNewlineWriter(1, &buf)
+	defer w.Close()
+
+	if _, err := w.Write([]byte("already terminated\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // let the worker dequeue before Flush races it
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := buf.String(); got != "already terminated\n" {
+		t.Fatalf("got %q, want exactly one trailing newline", got)
+	}
+}
+
+func TestLineWriterSkipsEmptyAndNewlineOnlyInput(t *testing.T) {
+	var buf bytes.Buffer
+	lw := NewLineValidatingWriter(1, &buf)
+	defer lw.Close()
+
+	for _, p := range [][]byte{nil, []byte{}, []byte("\n"), []byte("   \n"), []byte("  ")} {
+		n, err := lw.Write(p)
+		if err != nil {
+			t.Fatalf("Write(%q): %v", p, err)
+		}
+		if n != len(p) {
+			t.Fatalf("Write(%q): got n=%d, want %d", p, n, len(p))
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := lw.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := buf.String(); got != "" {
+		t.Fatalf("got %q written downstream, want nothing", got)
+	}
+	if got := lw.Stats.EmptySkipped.Load(); got != 5 {
+		t.Fatalf("got EmptySkipped=%d, want 5", got)
+	}
+}
+
+func TestNewlineEnforcingWriterAssemblesByteAtATime(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewlineEnforcingWriter(&buf)
+
+	for _, b := range []byte("one\ntwo\n") {
+		if _, err := w.Write([]byte{b}); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	if got := buf.String(); got != "one\ntwo\n" {
+		t.Fatalf("got %q, want %q", got, "one\ntwo\n")
+	}
+}
+
+func TestNewlineEnforcingWriterForwardsAllCompleteLinesInOneWrite(t *testing.T) {
+	var buf bytes.Buffer
+	var forwarded []string
+	w := NewlineEnforcingWriter(writerFunc(func(p []byte) (int, error) {
+		forwarded = append(forwarded, string(p))
+		return buf.Write(p)
+	}))
+
+	if _, err := w.Write([]byte("alpha\nbeta\ngam")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if len(forwarded) != 2 || forwarded[0] != "alpha\n" || forwarded[1] != "beta\n" {
+		t.Fatalf("got forwarded %q, want [%q %q]", forwarded, "alpha\n", "beta\n")
+	}
+
+	if _, err := w.Write([]byte("ma\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if len(forwarded) != 3 || forwarded[2] != "gamma\n" {
+		t.Fatalf("got forwarded %q, want a third line %q", forwarded, "gamma\n")
+	}
+	if got := buf.String(); got != "alpha\nbeta\ngamma\n" {
+		t.Fatalf("got %q, want %q", got, "alpha\nbeta\ngamma\n")
+	}
+}
+
+func TestNewlineEnforcingWriterHoldsUnterminatedFragment(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewlineEnforcingWriter(&buf)
+
+	if _, err := w.Write([]byte("no newline yet")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := buf.String(); got != "" {
+		t.Fatalf("got %q forwarded, want nothing until the line is complete", got)
+	}
+
+	if _, err := w.Write([]byte(" -- now it is\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if got := buf.String(); got != "no newline yet -- now it is\n" {
+		t.Fatalf("got %q, want the assembled line", got)
+	}
+}
+
+// writerFunc adapts a function to io.Writer, for tests that need to
+// observe each individual Write call rather than just the accumulated
+// bytes a bytes.Buffer would show.
+type writerFunc func(p []byte) (int, error)
+
+func (f writerFunc) Write(p []byte) (int, error) { return f(p) }
+
+func TestLineWriterRejectsMissingNewline(t *testing.T) {
+	var buf bytes.Buffer
+	lw := NewLineValidatingWriter(1, &buf)
+	defer lw.Close()
+
+	_, err := lw.Write([]byte("no trailing newline"))
+	if !errors.Is(err, ErrMissingNewline) {
+		t.Fatalf("got %v, want ErrMissingNewline", err)
+	}
+	if got := lw.Stats.NewlineRejected.Load(); got != 1 {
+		t.Fatalf("got NewlineRejected=%d, want 1", got)
+	}
+
+	if _, err := lw.Write([]byte("a real line\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := lw.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := buf.String(); got != "a real line\n" {
+		t.Fatalf("got %q, want only the valid line", got)
+	}
+}
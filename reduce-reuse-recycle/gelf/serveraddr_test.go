@@ -0,0 +1,63 @@
+package gelf
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestSetServerAddrNeverSplitsAMultiChunkMessage runs many concurrent
+// multi-chunk writes against a client whose destination is being
+// swapped concurrently by SetServerAddr, and asserts that every chunk
+// belonging to a single chunk-group ID landed at the same address --
+// the destination snapshot each write takes once at its start must never
+// be split mid-message by a concurrent swap. Run with -race.
+func TestSetServerAddrNeverSplitsAMultiChunkMessage(t *testing.T) {
+	conn := &memConn{}
+	addrA := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1}
+	addrB := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 2}
+	c := newClient(conn, addrA, Config{DisableCompression: true})
+
+	const writers = 8
+	var wg sync.WaitGroup
+	wg.Add(writers + 1)
+
+	payload := strings.Repeat("x", defaultMaxChunkSize*3)
+	for i := 0; i < writers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_ = c.WriteMessage(&Message{ShortMessage: payload})
+			}
+		}()
+	}
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 200; j++ {
+			if j%2 == 0 {
+				c.SetServerAddr(addrA)
+			} else {
+				c.SetServerAddr(addrB)
+			}
+		}
+	}()
+	wg.Wait()
+
+	packets, addrs := conn.PacketsWithAddrs()
+	groupAddr := make(map[[8]byte]net.Addr)
+	for i, p := range packets {
+		if !isChunkPacket(p) {
+			continue
+		}
+		var id [8]byte
+		copy(id[:], p[2:10])
+		if want, ok := groupAddr[id]; ok {
+			if addrs[i].String() != want.String() {
+				t.Fatalf("chunk group %x split across addresses %v and %v", id, want, addrs[i])
+			}
+		} else {
+			groupAddr[id] = addrs[i]
+		}
+	}
+}
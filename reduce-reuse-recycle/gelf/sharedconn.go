@@ -0,0 +1,81 @@
+package gelf
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// SharedConn wraps a net.PacketConn so multiple Clients can be built
+// on top of the same underlying socket without one Client's Close
+// pulling it out from under the others. Each Client constructed with
+// it via NewClientWithConn implicitly Acquires a reference; Close
+// (Client.Close, which calls SharedConn.Close) only closes the
+// underlying conn once every acquired reference has been released.
+type SharedConn struct {
+	conn net.PacketConn
+
+	mu     sync.Mutex
+	refs   int
+	closed bool
+}
+
+// NewSharedConn wraps conn for sharing across multiple Clients. It
+// starts with zero references; NewClientWithConn acquires one for
+// each Client built on it.
+func NewSharedConn(conn net.PacketConn) *SharedConn {
+	return &SharedConn{conn: conn}
+}
+
+// Acquire adds one reference, deferring the underlying conn's closure
+// until a matching Release (via Close) is seen for it.
+func (s *SharedConn) Acquire() {
+	s.mu.Lock()
+	s.refs++
+	s.mu.Unlock()
+}
+
+// Release drops one reference, closing the underlying conn once none
+// remain. It's equivalent to Close, and exists so callers that never
+// go through a Client (or want to release without the io.Closer name)
+// have a clearer name for the same operation.
+func (s *SharedConn) Release() error {
+	return s.Close()
+}
+
+func (s *SharedConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	return s.conn.WriteTo(b, addr)
+}
+
+func (s *SharedConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	return s.conn.ReadFrom(b)
+}
+
+func (s *SharedConn) LocalAddr() net.Addr { return s.conn.LocalAddr() }
+
+func (s *SharedConn) SetDeadline(t time.Time) error      { return s.conn.SetDeadline(t) }
+func (s *SharedConn) SetReadDeadline(t time.Time) error  { return s.conn.SetReadDeadline(t) }
+func (s *SharedConn) SetWriteDeadline(t time.Time) error { return s.conn.SetWriteDeadline(t) }
+
+// Close releases one reference, closing the underlying conn only once
+// every Client sharing it has released its reference. Calling Close
+// more times than references were acquired closes the underlying conn
+// on the first such call and is a no-op after.
+func (s *SharedConn) Close() error {
+	s.mu.Lock()
+	if s.refs > 0 {
+		s.refs--
+	}
+	closeNow := s.refs == 0 && !s.closed
+	if closeNow {
+		s.closed = true
+	}
+	s.mu.Unlock()
+
+	if closeNow {
+		return s.conn.Close()
+	}
+	return nil
+}
+
+var _ net.PacketConn = (*SharedConn)(nil)
@@ -0,0 +1,68 @@
+package gelf
+
+import (
+	"net"
+	"time"
+
+	"testing"
+)
+
+// countCloseConn is a net.PacketConn stub that just counts Close
+// calls, for asserting SharedConn's refcounting reaches the
+// underlying conn exactly once.
+type countCloseConn struct {
+	memConn
+	closes int
+}
+
+func (c *countCloseConn) Close() error {
+	c.closes++
+	return c.memConn.Close()
+}
+
+func (c *countCloseConn) ReadFrom(b []byte) (int, net.Addr, error) { return 0, nil, net.ErrClosed }
+func (c *countCloseConn) LocalAddr() net.Addr                      { return testAddr }
+func (c *countCloseConn) SetDeadline(t time.Time) error            { return nil }
+func (c *countCloseConn) SetReadDeadline(t time.Time) error        { return nil }
+func (c *countCloseConn) SetWriteDeadline(t time.Time) error       { return nil }
+
+func TestSharedConnClosesOnlyAfterLastClientReleases(t *testing.T) {
+	underlying := &countCloseConn{}
+	shared := NewSharedConn(underlying)
+
+	a := NewClientWithConn(shared, testAddr, Config{DisableCompression: true})
+	b := NewClientWithConn(shared, testAddr, Config{DisableCompression: true})
+	c := NewClientWithConn(shared, testAddr, Config{DisableCompression: true})
+
+	if err := a.WriteMessage(&Message{ShortMessage: "from a"}); err != nil {
+		t.Fatalf("a.WriteMessage: %v", err)
+	}
+	if err := b.WriteMessage(&Message{ShortMessage: "from b"}); err != nil {
+		t.Fatalf("b.WriteMessage: %v", err)
+	}
+
+	if err := a.Close(); err != nil {
+		t.Fatalf("a.Close: %v", err)
+	}
+	if underlying.closes != 0 {
+		t.Fatalf("underlying conn closed after first of three clients closed")
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatalf("b.Close: %v", err)
+	}
+	if underlying.closes != 0 {
+		t.Fatalf("underlying conn closed after second of three clients closed")
+	}
+
+	if err := c.WriteMessage(&Message{ShortMessage: "from c, still open"}); err != nil {
+		t.Fatalf("c.WriteMessage after a and b closed: %v", err)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close: %v", err)
+	}
+	if underlying.closes != 1 {
+		t.Fatalf("got %d underlying closes after last client closed, want 1", underlying.closes)
+	}
+}
@@ -0,0 +1,78 @@
+package gelf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+)
+
+// computeHMAC returns the hex-encoded HMAC-SHA256 of b under key.
+func computeHMAC(b, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(b)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signGELF computes an HMAC-SHA256 over b -- a complete GELF JSON
+// payload, as produced by Message.encode, with no "_signature" field
+// yet -- and re-encodes it with that signature added as "_signature".
+// The re-encode decodes into map[string]json.RawMessage rather than
+// map[string]interface{}, so every other field's bytes pass through
+// untouched instead of being unboxed to a Go value and re-marshaled --
+// unboxing a number into interface{} always lands it in a float64,
+// which only represents integers exactly up to 2^53 and would silently
+// corrupt any larger int64 Extra/Fields value, the exact bug AddInt's
+// dedicated int64 field exists to avoid (see fields.go). GELF JSON map
+// keys are always marshaled in sorted order, whether the map's values
+// are interface{} or json.RawMessage, so this is still a stable,
+// canonical base for the HMAC: a verifier can remove "_signature" and
+// re-marshal to reproduce exactly the same bytes the signer hashed. A
+// pre-existing "_signature" in Extra is silently overwritten.
+func signGELF(b, key []byte) ([]byte, error) {
+	sig := computeHMAC(b, key)
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	sigJSON, err := json.Marshal(sig)
+	if err != nil {
+		return nil, err
+	}
+	fields["_signature"] = sigJSON
+	return json.Marshal(fields)
+}
+
+// VerifyGELF checks a GELF JSON payload's "_signature" field against
+// an HMAC-SHA256 of the rest of the payload, computed the way
+// Config.SignKey causes a Client to compute it when sending. Like
+// signGELF, it decodes into map[string]json.RawMessage rather than
+// map[string]interface{}, so reconstructing the canonical payload
+// never unboxes a field's value and risks rounding it through float64.
+// It reports false, with no error, for a payload that's valid JSON but
+// doesn't verify; a non-nil error means jsonBytes isn't valid JSON, or
+// has no "_signature" field to check at all.
+func VerifyGELF(jsonBytes []byte, key []byte) (bool, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &fields); err != nil {
+		return false, err
+	}
+	sigRaw, ok := fields["_signature"]
+	if !ok {
+		return false, errors.New("gelf: payload has no _signature field")
+	}
+	var sig string
+	if err := json.Unmarshal(sigRaw, &sig); err != nil {
+		return false, err
+	}
+	delete(fields, "_signature")
+
+	canonical, err := json.Marshal(fields)
+	if err != nil {
+		return false, err
+	}
+	want := computeHMAC(canonical, key)
+	return hmac.Equal([]byte(sig), []byte(want)), nil
+}
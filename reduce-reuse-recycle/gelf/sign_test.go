@@ -0,0 +1,123 @@
+package gelf
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSignKeyRoundTripsThroughVerifyGELF(t *testing.T) {
+	conn := &memConn{}
+	key := []byte("super-secret-key")
+	c := newClient(conn, testAddr, Config{DisableCompression: true, SignKey: key})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "signed"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packet := conn.Packets()[0]
+	var fields map[string]interface{}
+	if err := json.Unmarshal(packet, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if _, ok := fields["_signature"]; !ok {
+		t.Fatalf("expected a _signature field, got %v", fields)
+	}
+
+	ok, err := VerifyGELF(packet, key)
+	if err != nil {
+		t.Fatalf("VerifyGELF: %v", err)
+	}
+	if !ok {
+		t.Fatalf("VerifyGELF reported an invalid signature for an unmodified payload")
+	}
+}
+
+func TestSignKeyPreservesFullInt64PrecisionInExtra(t *testing.T) {
+	conn := &memConn{}
+	key := []byte("super-secret-key")
+	c := newClient(conn, testAddr, Config{DisableCompression: true, SignKey: key})
+
+	const big int64 = 9007199254740993 // 2^53 + 1, outside float64's exact integer range
+	m := &Message{Host: "worker-1", ShortMessage: "signed", Extra: map[string]interface{}{"big": big}}
+	if err := c.WriteMessage(m); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packet := conn.Packets()[0]
+	var decoded struct {
+		Big int64 `json:"_big"`
+	}
+	if err := json.Unmarshal(packet, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if decoded.Big != big {
+		t.Fatalf("got _big=%d on the signed wire payload, want %d -- signGELF must not round-trip through float64", decoded.Big, big)
+	}
+
+	ok, err := VerifyGELF(packet, key)
+	if err != nil {
+		t.Fatalf("VerifyGELF: %v", err)
+	}
+	if !ok {
+		t.Fatalf("VerifyGELF reported an invalid signature for an unmodified payload")
+	}
+}
+
+func TestVerifyGELFDetectsTampering(t *testing.T) {
+	conn := &memConn{}
+	key := []byte("super-secret-key")
+	c := newClient(conn, testAddr, Config{DisableCompression: true, SignKey: key})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "original"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	packet := conn.Packets()[0]
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(packet, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	fields["short_message"] = "tampered"
+	tampered, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	ok, err := VerifyGELF(tampered, key)
+	if err != nil {
+		t.Fatalf("VerifyGELF: %v", err)
+	}
+	if ok {
+		t.Fatalf("VerifyGELF reported a tampered payload as valid")
+	}
+}
+
+func TestVerifyGELFRejectsWrongKey(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, SignKey: []byte("right-key")})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "x"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	ok, err := VerifyGELF(conn.Packets()[0], []byte("wrong-key"))
+	if err != nil {
+		t.Fatalf("VerifyGELF: %v", err)
+	}
+	if ok {
+		t.Fatalf("VerifyGELF accepted the wrong key")
+	}
+}
+
+func TestVerifyGELFErrorsWithoutSignatureField(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "unsigned"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if _, err := VerifyGELF(conn.Packets()[0], []byte("key")); err == nil {
+		t.Fatalf("expected an error verifying an unsigned payload")
+	}
+}
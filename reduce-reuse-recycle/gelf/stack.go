@@ -0,0 +1,50 @@
+package gelf
+
+import (
+	"runtime"
+	"strings"
+)
+
+// stackBufferDefault is the capture buffer size used when
+// Config.StackBufferSize is unset.
+const stackBufferDefault = 8192
+
+// gelfImportPath identifies this package's own frames in a captured
+// stack trace, so captureStack can skip past them to the caller.
+const gelfImportPath = "github.com/jessecarl/talks/reduce-reuse-recycle/gelf."
+
+// captureStack returns the calling goroutine's stack trace as text,
+// with this package's own frames stripped from the top so the trace
+// starts at the code that actually triggered it, bounded to maxBytes
+// (or stackBufferDefault if maxBytes <= 0). A trace too large for the
+// buffer is truncated by runtime.Stack itself; captureStack doesn't
+// retry with a larger one, trading completeness for a predictable
+// upper bound on message size.
+func captureStack(maxBytes int) string {
+	if maxBytes <= 0 {
+		maxBytes = stackBufferDefault
+	}
+	buf := make([]byte, maxBytes)
+	n := runtime.Stack(buf, false)
+	return stripOwnFrames(string(buf[:n]))
+}
+
+// stripOwnFrames removes leading two-line frames (function signature
+// plus file:line) that belong to this package from trace, which
+// otherwise starts with a "goroutine N [state]:" header line. It stops
+// at the first frame outside the package, or if the trace is too
+// short to contain any full frames.
+func stripOwnFrames(trace string) string {
+	lines := strings.Split(trace, "\n")
+	if len(lines) == 0 {
+		return trace
+	}
+
+	rest := lines[1:]
+	for len(rest) >= 2 && strings.Contains(rest[0], gelfImportPath) {
+		rest = rest[2:]
+	}
+
+	out := append(lines[:1:1], rest...)
+	return strings.Join(out, "\n")
+}
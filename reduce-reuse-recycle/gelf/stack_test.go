@@ -0,0 +1,65 @@
+package gelf
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStackOnErrorAttachesTraceAtOrBelowThreshold(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, StackOnError: 3})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "info", Level: 6}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := c.WriteMessage(&Message{ShortMessage: "error", Level: 3}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 2 {
+		t.Fatalf("got %d packets, want 2", len(packets))
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(packets[0], &info); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if _, ok := info["full_message"]; ok {
+		t.Fatalf("info-level event unexpectedly got a stack trace: %v", info["full_message"])
+	}
+
+	var errEvt map[string]interface{}
+	if err := json.Unmarshal(packets[1], &errEvt); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	full, _ := errEvt["full_message"].(string)
+	if !strings.Contains(full, "goroutine") {
+		t.Fatalf("got full_message=%q, want a captured stack trace", full)
+	}
+	if strings.Contains(full, gelfImportPath) {
+		t.Fatalf("stack trace still contains this package's own frames: %q", full)
+	}
+}
+
+func TestStackOnErrorUsesExtraFieldWhenFullMessageAlreadySet(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true, StackOnError: 3})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "error", FullMessage: "already set", Level: 2}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var evt map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &evt); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got, want := evt["full_message"], "already set"; got != want {
+		t.Fatalf("got full_message=%v, want %q unchanged", got, want)
+	}
+	trace, _ := evt["_stacktrace"].(string)
+	if !strings.Contains(trace, "goroutine") {
+		t.Fatalf("got _stacktrace=%q, want a captured stack trace", trace)
+	}
+}
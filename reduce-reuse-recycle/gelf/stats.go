@@ -0,0 +1,91 @@
+package gelf
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// compressionRatioAlpha weights the EWMA Stats.CompressionRatio uses:
+// closer to 1 tracks recent payloads more tightly, closer to 0 smooths
+// out bursts of unusually (in)compressible messages.
+const compressionRatioAlpha = 0.2
+
+// Stats holds running counters for a Client. All fields/methods are
+// safe for concurrent use.
+type Stats struct {
+	Sent    atomic.Uint64
+	Dropped atomic.Uint64
+	Errors  atomic.Uint64
+
+	// StaleDropped counts messages an AsyncClient discarded at dequeue
+	// time because they'd sat in the queue longer than Config.MessageTTL.
+	StaleDropped atomic.Uint64
+
+	// UncompressedBytes is the total size, before compression, of every
+	// encoded GELF payload accepted for sending. BytesSent is its wire
+	// counterpart; comparing the two gives operators ingestion volume
+	// as Graylog sees it (decompressed) versus network volume.
+	UncompressedBytes atomic.Uint64
+
+	// BytesSent is the total size of every payload actually handed to
+	// the transport -- post-compression when compression was used,
+	// pre-chunk-header otherwise. See UncompressedBytes.
+	BytesSent atomic.Uint64
+
+	ratioMu  sync.Mutex
+	ratio    float64
+	ratioSet bool
+}
+
+// recordCompressionRatio folds one payload's uncompressed/compressed
+// byte counts into the running EWMA. It's a no-op for a zero-length
+// payload.
+func (s *Stats) recordCompressionRatio(uncompressed, compressed int) {
+	if uncompressed <= 0 || compressed <= 0 {
+		return
+	}
+	sample := float64(uncompressed) / float64(compressed)
+
+	s.ratioMu.Lock()
+	defer s.ratioMu.Unlock()
+	if !s.ratioSet {
+		s.ratio = sample
+		s.ratioSet = true
+		return
+	}
+	s.ratio = compressionRatioAlpha*sample + (1-compressionRatioAlpha)*s.ratio
+}
+
+// CompressionRatio returns the EWMA of uncompressed/compressed bytes
+// across writes that were actually compressed. It's 0 until the first
+// such write.
+func (s *Stats) CompressionRatio() float64 {
+	s.ratioMu.Lock()
+	defer s.ratioMu.Unlock()
+	return s.ratio
+}
+
+// StatsSnapshot is a point-in-time, pre-drain copy of Stats' plain
+// counters, returned by Client.DrainStats.
+type StatsSnapshot struct {
+	Sent              uint64
+	Dropped           uint64
+	Errors            uint64
+	StaleDropped      uint64
+	UncompressedBytes uint64
+	BytesSent         uint64
+}
+
+// snapshot returns a point-in-time copy of the counters, suitable for
+// embedding in a message.
+func (s *Stats) snapshot() map[string]interface{} {
+	return map[string]interface{}{
+		"sent":               s.Sent.Load(),
+		"dropped":            s.Dropped.Load(),
+		"errors":             s.Errors.Load(),
+		"stale_dropped":      s.StaleDropped.Load(),
+		"compression_ratio":  s.CompressionRatio(),
+		"uncompressed_bytes": s.UncompressedBytes.Load(),
+		"bytes_sent":         s.BytesSent.Load(),
+	}
+}
@@ -0,0 +1,69 @@
+package gelf
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDrainStatsLosesNoCountsUnderConcurrentLoad(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	const goroutines = 8
+	const perGoroutine = 1000
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				c.Stats.Sent.Add(1)
+				c.Stats.Dropped.Add(1)
+				c.Stats.Errors.Add(1)
+			}
+		}()
+	}
+
+	var drainedSent, drainedDropped, drainedErrors uint64
+	var drainMu sync.Mutex
+	done := make(chan struct{})
+	var drainWg sync.WaitGroup
+	drainWg.Add(1)
+	go func() {
+		defer drainWg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				snap := c.DrainStats()
+				drainMu.Lock()
+				drainedSent += snap.Sent
+				drainedDropped += snap.Dropped
+				drainedErrors += snap.Errors
+				drainMu.Unlock()
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	drainWg.Wait()
+
+	final := c.DrainStats()
+	drainedSent += final.Sent
+	drainedDropped += final.Dropped
+	drainedErrors += final.Errors
+
+	want := uint64(goroutines * perGoroutine)
+	if drainedSent != want {
+		t.Fatalf("got total Sent=%d across drains, want %d", drainedSent, want)
+	}
+	if drainedDropped != want {
+		t.Fatalf("got total Dropped=%d across drains, want %d", drainedDropped, want)
+	}
+	if drainedErrors != want {
+		t.Fatalf("got total Errors=%d across drains, want %d", drainedErrors, want)
+	}
+}
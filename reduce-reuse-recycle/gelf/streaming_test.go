@@ -0,0 +1,82 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func TestClientStreamingCompressionSendsReassemblableMessage(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{StreamingCompression: true})
+
+	raw := make([]byte, 80000)
+	rand.New(rand.NewSource(1)).Read(raw)
+	short := &Message{ShortMessage: base64.StdEncoding.EncodeToString(raw)}
+	if err := c.WriteMessage(short); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) < 2 {
+		t.Fatalf("expected a chunked message, got %d packet(s)", len(packets))
+	}
+	assertContiguousChunkSequence(t, packets)
+
+	var compressed bytes.Buffer
+	for _, p := range packets {
+		compressed.Write(p[chunkHeaderLen:])
+	}
+
+	zr, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading reassembled gzip stream: %v", err)
+	}
+	if !bytes.Contains(out, []byte(`"short_message"`)) {
+		t.Fatalf("reassembled payload doesn't look like a GELF message: %q", out)
+	}
+}
+
+func TestClientStreamingCompressionHonorsMaxInFlightBytes(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{
+		StreamingCompression: true,
+		MaxInFlightBytes:     defaultMaxChunkSize * 2,
+	})
+
+	raw := make([]byte, 80000)
+	rand.New(rand.NewSource(1)).Read(raw)
+	short := &Message{ShortMessage: base64.StdEncoding.EncodeToString(raw)}
+	if err := c.WriteMessage(short); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) < 2 {
+		t.Fatalf("expected a chunked message, got %d packet(s)", len(packets))
+	}
+	assertContiguousChunkSequence(t, packets)
+
+	var compressed bytes.Buffer
+	for _, p := range packets {
+		compressed.Write(p[chunkHeaderLen:])
+	}
+	zr, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	out, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("reading reassembled gzip stream: %v", err)
+	}
+	if !bytes.Contains(out, []byte(`"short_message"`)) {
+		t.Fatalf("reassembled payload doesn't look like a GELF message: %q", out)
+	}
+}
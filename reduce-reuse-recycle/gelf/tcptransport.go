@@ -0,0 +1,60 @@
+package gelf
+
+import (
+	"fmt"
+	"net"
+)
+
+// tcpTransport is a Transport for GELF's stream-oriented TCP input:
+// one JSON object per message, terminated by a single null byte, with
+// none of UDP's chunk headers or magic bytes. A TCP input reads one
+// continuous connection rather than independent datagrams, so there's
+// nothing to chunk and no MTU to stay under -- Client disables both
+// whenever it builds a stream transport; see Client.streamMode.
+type tcpTransport struct {
+	conn net.Conn
+}
+
+// Send writes packets -- always exactly one, since Client never
+// chunks in stream mode -- to conn, appending a trailing null byte.
+// More than one packet would mean Client's own streamMode gating is
+// broken, not a caller error, so that case panics rather than
+// silently framing each packet separately (which a GELF TCP input
+// would read back as more than one message) or merging them (which
+// would corrupt both).
+func (t *tcpTransport) Send(packets [][]byte) error {
+	if len(packets) != 1 {
+		panic(fmt.Sprintf("gelf: tcpTransport.Send got %d packets, want exactly 1", len(packets)))
+	}
+	framed := append(append([]byte{}, packets[0]...), 0)
+	_, err := t.conn.Write(framed)
+	return err
+}
+
+func (t *tcpTransport) Close() error { return t.conn.Close() }
+
+// NewTCPClient dials addr over TCP and returns a Client that frames
+// each message with a trailing null byte instead of UDP's chunk
+// headers, for Graylog's GELF TCP input. Unlike NewClient's UDP path,
+// messages are never split (a stream has no MTU to respect) and never
+// gzip-compressed (GELF TCP inputs expect raw, newline-free JSON, not
+// a compressed stream) -- see Client.streamMode.
+func NewTCPClient(addr string, cfg Config) (*Client, error) {
+	var conn net.Conn
+	err := dialWithRetry(cfg, func() error {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			return err
+		}
+		conn = c
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Transport = &tcpTransport{conn: conn}
+	c := newClient(nil, conn.RemoteAddr(), cfg)
+	c.streamMode = true
+	return c, nil
+}
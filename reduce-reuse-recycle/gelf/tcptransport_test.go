@@ -0,0 +1,99 @@
+package gelf
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestTCPClientFramesLargeMessageWithNullByteAndNoChunking(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		frame, err := bufio.NewReader(conn).ReadBytes(0)
+		if err != nil {
+			return
+		}
+		received <- frame[:len(frame)-1]
+	}()
+
+	c, err := NewTCPClient(ln.Addr().String(), Config{})
+	if err != nil {
+		t.Fatalf("NewTCPClient: %v", err)
+	}
+	defer c.Close()
+
+	large := strings.Repeat("x", defaultMaxChunkSize*3)
+	if err := c.WriteMessage(&Message{ShortMessage: large}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	frame := <-received
+	if !strings.Contains(string(frame), large) {
+		t.Fatalf("received frame doesn't contain the full message")
+	}
+	if frame[0] == chunkMagic0 {
+		t.Fatalf("received frame starts with the GELF chunk magic byte; TCP must never chunk")
+	}
+	if frame[0] == 0x1f {
+		t.Fatalf("received frame starts with the gzip magic byte; TCP messages must be sent uncompressed")
+	}
+}
+
+func TestTCPClientDoesNotSplitMultipleMessagesAcrossFrames(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+
+	frames := make(chan []byte, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			frame, err := r.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			frames <- frame[:len(frame)-1]
+		}
+	}()
+
+	c, err := NewTCPClient(ln.Addr().String(), Config{})
+	if err != nil {
+		t.Fatalf("NewTCPClient: %v", err)
+	}
+	defer c.Close()
+
+	if err := c.WriteMessage(&Message{ShortMessage: "first"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if err := c.WriteMessage(&Message{ShortMessage: "second"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	first := <-frames
+	second := <-frames
+	if !strings.Contains(string(first), "first") {
+		t.Fatalf("got first frame %q, want it to contain %q", first, "first")
+	}
+	if !strings.Contains(string(second), "second") {
+		t.Fatalf("got second frame %q, want it to contain %q", second, "second")
+	}
+}
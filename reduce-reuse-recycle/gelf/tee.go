@@ -0,0 +1,47 @@
+package gelf
+
+import "errors"
+
+// TeeSink is one destination a TeeClient writes to: its own wire
+// format and its own transport.
+type TeeSink struct {
+	Encoder   Encoder
+	Transport Transport
+}
+
+// TeeClient sends one Message through several independent sinks, each
+// encoding it in its own format. It exists for migrations where the
+// same event needs to reach two differently-formatted destinations at
+// once (e.g. GELF JSON to a new graylog alongside plain text to a
+// legacy sink) — unlike MultiClient, which fans identically-encoded
+// bytes out to multiple destinations, every sink here encodes
+// independently.
+type TeeClient struct {
+	sinks []TeeSink
+}
+
+// NewTeeClient builds a TeeClient writing to sinks.
+func NewTeeClient(sinks ...TeeSink) *TeeClient {
+	return &TeeClient{sinks: sinks}
+}
+
+// WriteMessage encodes m once per sink and sends each encoding through
+// that sink's transport, continuing past a failed sink to give every
+// other sink a chance to receive the message. It returns the
+// aggregated errors, if any, via errors.Join.
+func (tc *TeeClient) WriteMessage(m *Message) error {
+	m.ensureExtra()
+
+	var errs []error
+	for _, s := range tc.sinks {
+		b, err := s.Encoder.Encode(m)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := s.Transport.Send([][]byte{b}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
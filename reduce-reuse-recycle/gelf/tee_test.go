@@ -0,0 +1,75 @@
+package gelf
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTeeClientSendsEachSinkItsOwnEncoding(t *testing.T) {
+	jsonTransport := &memTransport{}
+	logfmtTransport := &memTransport{}
+
+	tc := NewTeeClient(
+		TeeSink{Encoder: &gelfJSONEncoder{autoPrefix: true}, Transport: jsonTransport},
+		TeeSink{Encoder: LogfmtEncoder{}, Transport: logfmtTransport},
+	)
+
+	m := &Message{ShortMessage: "migrating", Host: "h", Level: 6}
+	if err := tc.WriteMessage(m); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	if len(jsonTransport.batches) != 1 || len(jsonTransport.batches[0]) != 1 {
+		t.Fatalf("expected one packet sent to the JSON sink, got %v", jsonTransport.batches)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(jsonTransport.batches[0][0], &decoded); err != nil {
+		t.Fatalf("JSON sink payload isn't valid GELF JSON: %v", err)
+	}
+	if decoded["short_message"] != "migrating" {
+		t.Fatalf("got %v", decoded)
+	}
+
+	if len(logfmtTransport.batches) != 1 || len(logfmtTransport.batches[0]) != 1 {
+		t.Fatalf("expected one packet sent to the logfmt sink, got %v", logfmtTransport.batches)
+	}
+	got := string(logfmtTransport.batches[0][0])
+	if !strings.Contains(got, `message=migrating`) {
+		t.Fatalf("logfmt sink payload missing expected field, got %q", got)
+	}
+}
+
+func TestTeeClientAggregatesErrorsButSendsToEverySink(t *testing.T) {
+	good := &memTransport{}
+	bad := &memTransport{}
+
+	tc := NewTeeClient(
+		TeeSink{Encoder: &gelfJSONEncoder{autoPrefix: true}, Transport: good},
+		TeeSink{Encoder: &gelfJSONEncoder{autoPrefix: true}, Transport: &failingTransport{inner: bad}},
+	)
+
+	err := tc.WriteMessage(&Message{ShortMessage: "x"})
+	if err == nil {
+		t.Fatalf("expected an aggregated error from the failing sink")
+	}
+	if len(good.batches) != 1 {
+		t.Fatalf("expected the working sink to still receive the message, got %v", good.batches)
+	}
+}
+
+// failingTransport wraps another Transport but always reports an
+// error, regardless of whether the wrapped Transport succeeded.
+type failingTransport struct {
+	inner Transport
+}
+
+func (t *failingTransport) Send(packets [][]byte) error {
+	t.inner.Send(packets)
+	return errTestFailingTransport
+}
+
+func (t *failingTransport) Close() error { return t.inner.Close() }
+
+var errTestFailingTransport = errors.New("gelf: test transport always fails")
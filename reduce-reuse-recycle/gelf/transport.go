@@ -0,0 +1,162 @@
+package gelf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// deadlineSetter is the subset of net.PacketConn a conn must support
+// for Config.WriteDeadlineBase to take effect. Checked with a type
+// assertion rather than folded into packetConn, since most test fakes
+// and some real custom conns have no use for deadlines at all.
+type deadlineSetter interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// Transport is the pluggable backend a Client writes its (already
+// compressed and chunked) GELF packets to. The built-in UDP behavior
+// is udpTransport, built automatically around the client's conn;
+// Config.Transport lets callers substitute TCP, TLS, or any other
+// backend without touching the encoding/compression/chunking
+// pipeline above it.
+type Transport interface {
+	// Send writes packets, the chunks of one GELF message in order,
+	// to the transport's destination. It returns the first error
+	// encountered, unwrapped so callers relying on errors.Is (e.g.
+	// EMSGSIZE detection) still work for transports where that
+	// applies.
+	Send(packets [][]byte) error
+
+	// Close releases the transport's underlying connection.
+	Close() error
+}
+
+// ContextTransport is a Transport that can honor a caller's
+// context.Context: SendContext should check ctx between chunk writes
+// and stop early on cancellation, rather than running the whole send
+// to completion regardless. Client.WriteContext checks for this via a
+// type assertion on Config.Transport and falls back to plain Send for
+// a Transport that doesn't implement it, so adding SendContext to an
+// existing Transport is backward compatible.
+type ContextTransport interface {
+	Transport
+
+	// SendContext is Send, additionally stopping early with
+	// ctx.Err() (wrapped in *ErrSendCanceled) if ctx is done before
+	// every packet has been written.
+	SendContext(ctx context.Context, packets [][]byte) error
+}
+
+// udpTransport is the default Transport, adapting a packetConn (real
+// UDP or a test fake) to the Transport interface.
+type udpTransport struct {
+	conn packetConn
+	addr net.Addr
+}
+
+func (t *udpTransport) Send(packets [][]byte) error {
+	return t.SendContext(context.Background(), packets)
+}
+
+func (t *udpTransport) SendContext(ctx context.Context, packets [][]byte) error {
+	bytesSent := 0
+	for seq, p := range packets {
+		if err := ctx.Err(); err != nil {
+			return &ErrSendCanceled{Sent: seq, Count: len(packets), BytesSent: bytesSent, Err: err}
+		}
+		n, err := t.conn.WriteTo(p, t.addr)
+		switch {
+		case errors.Is(err, io.ErrShortWrite):
+			return &ChunkWriteError{Seq: seq, Count: len(packets), Err: err}
+		case err != nil:
+			if errors.Is(err, net.ErrClosed) {
+				return fmt.Errorf("gelf: write to closed connection: %w", err)
+			}
+			return err
+		case n < len(p):
+			// Some PacketConn implementations signal a short write by
+			// returning n < len(p) with a nil error, rather than
+			// io.ErrShortWrite; treat that the same way.
+			return &ChunkWriteError{Seq: seq, Count: len(packets), Err: io.ErrShortWrite}
+		}
+		bytesSent += n
+	}
+	return nil
+}
+
+func (t *udpTransport) Close() error { return t.conn.Close() }
+
+// ErrSendCanceled reports that ctx was done before every chunk of a
+// multi-chunk GELF message could be written. Sent and Count describe
+// which chunk, of how many, was skipped; BytesSent is the payload
+// already written to the wire across the earlier, successful chunks
+// -- a receiver holding those chunks can never reassemble the message
+// they belong to, so BytesSent is reporting only, not a signal that
+// partial progress is usable.
+type ErrSendCanceled struct {
+	Sent, Count int
+	BytesSent   int
+	Err         error
+}
+
+func (e *ErrSendCanceled) Error() string {
+	return fmt.Sprintf("gelf: send canceled before chunk %d/%d (%d bytes already sent): %v", e.Sent+1, e.Count, e.BytesSent, e.Err)
+}
+
+func (e *ErrSendCanceled) Unwrap() error { return e.Err }
+
+// ChunkWriteError reports that one chunk of a multi-chunk GELF message
+// failed to write as a complete datagram -- most commonly because the
+// underlying conn returned (or behaved like) io.ErrShortWrite. Unlike
+// a transient send error, a short write on a datagram socket can't be
+// retried or completed piecemeal: a partial datagram is meaningless
+// to a reassembling receiver, so the chunk -- and the message it
+// belongs to -- is simply lost. Seq and Count identify which chunk, of
+// how many, failed.
+type ChunkWriteError struct {
+	Seq, Count int
+	Err        error
+}
+
+func (e *ChunkWriteError) Error() string {
+	return fmt.Sprintf("gelf: chunk %d/%d failed to write as a complete datagram: %v", e.Seq+1, e.Count, e.Err)
+}
+
+func (e *ChunkWriteError) Unwrap() error { return e.Err }
+
+// transportSend writes packets, the chunks of a single GELF message,
+// to addr via Config.Transport if one is configured, or directly over
+// the client's UDP conn otherwise. In the latter case, it first applies
+// Config.WriteDeadlineBase/WriteDeadlinePerChunk, if set and the conn
+// supports it. If Config.Transport implements ContextTransport, ctx is
+// honored for cancellation between chunks; otherwise it's only used for
+// the built-in UDP path.
+func (c *Client) transportSend(ctx context.Context, addr net.Addr, packets [][]byte) error {
+	if c.cfg.Transport != nil {
+		if ct, ok := c.cfg.Transport.(ContextTransport); ok {
+			return ct.SendContext(ctx, packets)
+		}
+		return c.cfg.Transport.Send(packets)
+	}
+	if deadline := c.writeDeadline(len(packets)); !deadline.IsZero() {
+		if ds, ok := c.conn.(deadlineSetter); ok {
+			_ = ds.SetWriteDeadline(deadline)
+		}
+	}
+	return (&udpTransport{conn: c.conn, addr: addr}).SendContext(ctx, packets)
+}
+
+// writeDeadline computes the absolute deadline for sending count
+// chunks, per Config.WriteDeadlineBase/WriteDeadlinePerChunk, or the
+// zero Time if no deadline is configured.
+func (c *Client) writeDeadline(count int) time.Time {
+	if c.cfg.WriteDeadlineBase <= 0 {
+		return time.Time{}
+	}
+	total := c.cfg.WriteDeadlineBase + c.cfg.WriteDeadlinePerChunk*time.Duration(count)
+	return c.clock().Now().Add(total)
+}
@@ -0,0 +1,161 @@
+package gelf
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// deadlineRecordingConn is a packetConn test double that also
+// implements SetWriteDeadline, recording every deadline it's given so
+// tests can assert on Config.WriteDeadlineBase/WriteDeadlinePerChunk
+// without a real slow conn.
+type deadlineRecordingConn struct {
+	memConn
+	deadlines []time.Time
+}
+
+func (d *deadlineRecordingConn) SetWriteDeadline(t time.Time) error {
+	d.deadlines = append(d.deadlines, t)
+	return nil
+}
+
+// shortWriteConn is a packetConn test double that reports a short
+// write on its Nth call to WriteTo, either via io.ErrShortWrite or by
+// returning n < len(p) with a nil error, depending on asErr.
+type shortWriteConn struct {
+	memConn
+	failOn int
+	asErr  bool
+	calls  int
+}
+
+func (s *shortWriteConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	s.calls++
+	if s.calls == s.failOn {
+		if s.asErr {
+			return 0, io.ErrShortWrite
+		}
+		return len(p) - 1, nil
+	}
+	return s.memConn.WriteTo(p, addr)
+}
+
+// memTransport is a Transport test double recording every packet
+// batch it's sent, so tests can assert on chunking without a UDP conn.
+type memTransport struct {
+	batches [][][]byte
+	closed  bool
+}
+
+func (t *memTransport) Send(packets [][]byte) error {
+	t.batches = append(t.batches, packets)
+	return nil
+}
+
+func (t *memTransport) Close() error {
+	t.closed = true
+	return nil
+}
+
+func TestClientUsesConfiguredTransport(t *testing.T) {
+	tr := &memTransport{}
+	c := newClient(nil, testAddr, Config{
+		Transport:          tr,
+		DisableCompression: true,
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	if len(tr.batches) != 1 || len(tr.batches[0]) != 1 {
+		t.Fatalf("expected one single-packet batch, got %v", tr.batches)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !tr.closed {
+		t.Fatalf("expected Close to close the configured Transport")
+	}
+}
+
+func TestWriteDeadlinePerChunkScalesWithChunkCount(t *testing.T) {
+	clock := newFakeClock(time.Unix(0, 0))
+	conn := &deadlineRecordingConn{}
+	c := newClient(conn, testAddr, Config{
+		DisableCompression:    true,
+		Clock:                 clock,
+		WriteDeadlineBase:     time.Second,
+		WriteDeadlinePerChunk: 500 * time.Millisecond,
+	})
+
+	if err := c.send(context.Background(), testAddr, []byte("small")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	small := make([]byte, defaultMaxChunkSize*3)
+	if err := c.send(context.Background(), testAddr, small); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	if len(conn.deadlines) != 2 {
+		t.Fatalf("got %d recorded deadlines, want 2", len(conn.deadlines))
+	}
+	wantSingle := clock.Now().Add(time.Second + 500*time.Millisecond)
+	wantChunked := clock.Now().Add(time.Second + 3*500*time.Millisecond)
+	if !conn.deadlines[0].Equal(wantSingle) {
+		t.Fatalf("single-chunk deadline = %v, want %v", conn.deadlines[0], wantSingle)
+	}
+	if !conn.deadlines[1].Equal(wantChunked) {
+		t.Fatalf("3-chunk deadline = %v, want %v", conn.deadlines[1], wantChunked)
+	}
+	if !conn.deadlines[1].After(conn.deadlines[0]) {
+		t.Fatalf("expected the larger message to get a proportionally later deadline")
+	}
+}
+
+func TestWriteDeadlineUnsetLeavesConnAlone(t *testing.T) {
+	conn := &deadlineRecordingConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.send(context.Background(), testAddr, []byte("small")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if len(conn.deadlines) != 0 {
+		t.Fatalf("expected no deadline set without Config.WriteDeadlineBase, got %v", conn.deadlines)
+	}
+}
+
+func TestUDPTransportReportsChunkWriteErrorOnShortWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		asErr bool
+	}{
+		{"io.ErrShortWrite", true},
+		{"n < len(p) with nil error", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &shortWriteConn{failOn: 2, asErr: tt.asErr}
+			c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+			payload := make([]byte, defaultMaxChunkSize*3)
+			err := c.send(context.Background(), testAddr, payload)
+
+			var cwErr *ChunkWriteError
+			if !errors.As(err, &cwErr) {
+				t.Fatalf("got %v, want *ChunkWriteError", err)
+			}
+			if cwErr.Seq != 1 || cwErr.Count != 3 {
+				t.Fatalf("got Seq=%d Count=%d, want Seq=1 Count=3", cwErr.Seq, cwErr.Count)
+			}
+			if !errors.Is(cwErr, io.ErrShortWrite) {
+				t.Fatalf("expected ChunkWriteError to unwrap to io.ErrShortWrite, got %v", cwErr.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package gelf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestAddUptimeAdvancesBetweenWrites(t *testing.T) {
+	conn := &memConn{}
+	clk := newFakeClock(time.Unix(0, 0))
+	c := newClient(conn, testAddr, Config{
+		DisableCompression: true,
+		AddUptime:          true,
+		Clock:              clk,
+	})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "first"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+	clk.Advance(90 * time.Second)
+	if err := c.WriteMessage(&Message{ShortMessage: "second"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 2 {
+		t.Fatalf("got %d packets, want 2", len(packets))
+	}
+
+	var first, second struct {
+		Uptime float64 `json:"_uptime_seconds"`
+	}
+	if err := json.Unmarshal(packets[0], &first); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if err := json.Unmarshal(packets[1], &second); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if first.Uptime != 0 {
+		t.Fatalf("got first uptime %v, want 0", first.Uptime)
+	}
+	if second.Uptime != 90 {
+		t.Fatalf("got second uptime %v, want 90", second.Uptime)
+	}
+}
+
+func TestAddUptimeOffByDefault(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	if err := c.WriteMessage(&Message{ShortMessage: "hi"}); err != nil {
+		t.Fatalf("WriteMessage: %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(conn.Packets()[0], &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if _, ok := fields["_uptime_seconds"]; ok {
+		t.Fatalf("got _uptime_seconds present, want it absent when AddUptime is unset")
+	}
+}
@@ -0,0 +1,67 @@
+package gelf
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// cancelAfterNConn is a packetConn that cancels cancel right after the
+// nth chunk has been written, for simulating a context canceled
+// partway through a multi-chunk send.
+type cancelAfterNConn struct {
+	memConn
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelAfterNConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.memConn.WriteTo(b, addr)
+	if len(c.memConn.Packets()) == c.n {
+		c.cancel()
+	}
+	return n, err
+}
+
+func TestWriteContextStopsSendingAfterCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &cancelAfterNConn{n: 1, cancel: cancel}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	m := &Message{
+		Host:         "worker-1",
+		ShortMessage: strings.Repeat("x", defaultMaxChunkSize*4),
+	}
+
+	err := c.WriteContext(ctx, m)
+	var canceled *ErrSendCanceled
+	if !errors.As(err, &canceled) {
+		t.Fatalf("got %v, want *ErrSendCanceled", err)
+	}
+	if canceled.Sent != 1 {
+		t.Fatalf("got Sent=%d, want 1 (canceled right after the first chunk went out)", canceled.Sent)
+	}
+
+	packets := conn.Packets()
+	if len(packets) != 1 {
+		t.Fatalf("got %d packets written, want exactly 1 -- later chunks must not be sent once ctx is canceled", len(packets))
+	}
+}
+
+func TestWriteContextRejectsAlreadyCanceledContext(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := c.WriteContext(ctx, &Message{Host: "worker-1", ShortMessage: "hi"})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if len(conn.Packets()) != 0 {
+		t.Fatalf("got %d packets sent, want 0 for an already-canceled context", len(conn.Packets()))
+	}
+}
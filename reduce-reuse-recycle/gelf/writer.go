@@ -0,0 +1,198 @@
+// Package gelf is the code sample accompanying the "Reduce, Reuse,
+// Recycle" talk: a small GELF (Graylog Extended Log Format) client that
+// explores a few strategies for cutting down per-message allocation on
+// a hot logging path.
+package gelf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrWriterClosed is returned by Write when the Writer's worker pool has
+// already been shut down.
+var ErrWriterClosed = errors.New("gelf: write to closed Writer")
+
+// ErrWriterRunning is returned by Reset when the worker pool has not
+// been shut down yet.
+var ErrWriterRunning = errors.New("gelf: Reset called while Writer is still running")
+
+// Writer is a concurrent io.Writer backed by a fixed pool of worker
+// goroutines. Each worker owns a long-lived bufio.Writer over the
+// underlying writer, so the buffer is allocated once per worker rather
+// than once per message.
+type Writer struct {
+	mu         sync.Mutex
+	uw         io.Writer
+	capacity   int
+	jobs       chan []byte
+	flushChans []chan chan error
+	wg         sync.WaitGroup
+	running    bool
+	remaining  atomic.Int32
+}
+
+// CloseTimeoutError is returned by CloseWithTimeout when its deadline
+// elapses before every worker has drained its buffer and exited. The
+// workers themselves are not abandoned -- they keep running and will
+// eventually finish on their own, draining whatever is stuck in uw.Write.
+type CloseTimeoutError struct {
+	Remaining int
+}
+
+func (e *CloseTimeoutError) Error() string {
+	return fmt.Sprintf("gelf: CloseWithTimeout timed out with %d worker(s) still running", e.Remaining)
+}
+
+// NewWriter starts a Writer with capacity worker goroutines writing to
+// uw.
+func NewWriter(capacity int, uw io.Writer) *Writer {
+	w := &Writer{}
+	w.start(capacity, uw)
+	return w
+}
+
+// start spawns the worker goroutines. Callers must hold w.mu or know no
+// other goroutine can observe w yet.
+func (w *Writer) start(capacity int, uw io.Writer) {
+	w.uw = uw
+	w.capacity = capacity
+	w.jobs = make(chan []byte, capacity)
+	w.flushChans = make([]chan chan error, capacity)
+	w.running = true
+	w.remaining.Store(int32(capacity))
+	w.wg.Add(capacity)
+	for i := 0; i < capacity; i++ {
+		fc := make(chan chan error)
+		w.flushChans[i] = fc
+		go w.work(fc)
+	}
+}
+
+// work runs a single worker's buffered write loop. Writes are buffered
+// until either the pool is closed or a flush is requested on fc, so a
+// flush control message never races with an in-flight Write.
+func (w *Writer) work(fc chan chan error) {
+	defer w.wg.Done()
+	defer w.remaining.Add(-1)
+	bw := bufio.NewWriter(w.uw)
+	for {
+		select {
+		case p, ok := <-w.jobs:
+			if !ok {
+				bw.Flush()
+				return
+			}
+			// Best-effort: a write error on one worker shouldn't take
+			// down the pool. Error reporting is covered by a later
+			// change.
+			bw.Write(p)
+		case respCh := <-fc:
+			respCh <- bw.Flush()
+		}
+	}
+}
+
+// Write enqueues p for a worker to send. It returns ErrWriterClosed if
+// the pool has been shut down. w.mu is held for the entire send, not
+// just the running check, so Close can never close w.jobs while a
+// Write is in the middle of sending on it -- Close blocks on the same
+// lock until this Write has either enqueued p or (if it lost the
+// running check) returned ErrWriterClosed.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.running {
+		return 0, ErrWriterClosed
+	}
+	w.jobs <- p
+	return len(p), nil
+}
+
+// Flush asks every worker to flush its buffered data to the underlying
+// writer and waits for them all to finish, returning the first error
+// encountered, if any. It is safe to call concurrently with Write.
+func (w *Writer) Flush() error {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	chans := w.flushChans
+	w.mu.Unlock()
+
+	var firstErr error
+	for _, fc := range chans {
+		respCh := make(chan error, 1)
+		fc <- respCh
+		if err := <-respCh; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close stops accepting writes and waits for every worker to drain its
+// buffer and exit.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	w.running = false
+	close(w.jobs)
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return nil
+}
+
+// CloseWithTimeout is Close, bounded by d: it waits for every worker to
+// drain its buffer and exit, but gives up and returns a
+// *CloseTimeoutError if they haven't all finished by the deadline. The
+// workers are not killed -- a worker stuck in a blocking uw.Write keeps
+// running after CloseWithTimeout returns and will still flush and exit
+// once that write unblocks, it's just no longer waited for here.
+func (w *Writer) CloseWithTimeout(d time.Duration) error {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return ErrWriterClosed
+	}
+	w.running = false
+	close(w.jobs)
+	w.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-time.After(d):
+		return &CloseTimeoutError{Remaining: int(w.remaining.Load())}
+	}
+}
+
+// Reset restarts the worker pool with a fresh capacity and underlying
+// writer so the same Writer can be reused for another run without
+// reallocating it from scratch. It returns ErrWriterRunning if called
+// before Close.
+func (w *Writer) Reset(capacity int, uw io.Writer) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.running {
+		return ErrWriterRunning
+	}
+	w.start(capacity, uw)
+	return nil
+}
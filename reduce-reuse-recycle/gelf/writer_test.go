@@ -0,0 +1,142 @@
+package gelf
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWriterResetAfterClose(t *testing.T) {
+	var buf1 bytes.Buffer
+	w := NewWriter(2, &buf1)
+
+	if _, err := w.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Write before close: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	var buf2 bytes.Buffer
+	if err := w.Reset(2, &buf2); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	if _, err := w.Write([]byte("second\n")); err != nil {
+		t.Fatalf("Write after reset: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close after reset: %v", err)
+	}
+
+	if buf2.Len() == 0 {
+		t.Fatalf("expected data written to the writer installed by Reset")
+	}
+}
+
+func TestWriterFlush(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(1, &buf)
+	defer w.Close()
+
+	if _, err := w.Write([]byte("buffered\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// Give the worker a chance to dequeue the write before we flush, so
+	// Flush observes it buffered rather than still in the jobs channel.
+	time.Sleep(10 * time.Millisecond)
+
+	// bufio buffers by default, so nothing should have reached buf yet.
+	if buf.Len() != 0 {
+		t.Fatalf("expected no data before Flush, got %d bytes", buf.Len())
+	}
+
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if buf.Len() == 0 {
+		t.Fatalf("expected buffered data to reach the sink after Flush")
+	}
+}
+
+// blockingWriter never returns from Write until release is closed,
+// simulating a sink that's stuck (a wedged socket, a hung remote).
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (b *blockingWriter) Write(p []byte) (int, error) {
+	<-b.release
+	return len(p), nil
+}
+
+func TestWriterCloseWithTimeoutReportsRemainingOnExpiry(t *testing.T) {
+	sink := &blockingWriter{release: make(chan struct{})}
+	w := NewWriter(1, sink)
+	defer close(sink.release)
+
+	if _, err := w.Write([]byte("stuck\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// Force the buffered write out to the blocking sink.
+	go w.Flush()
+	time.Sleep(10 * time.Millisecond)
+
+	err := w.CloseWithTimeout(20 * time.Millisecond)
+	var timeoutErr *CloseTimeoutError
+	if err == nil {
+		t.Fatalf("CloseWithTimeout: got nil error, want a timeout with the worker still blocked")
+	}
+	if ce, ok := err.(*CloseTimeoutError); !ok {
+		t.Fatalf("got error type %T, want *CloseTimeoutError", err)
+	} else {
+		timeoutErr = ce
+	}
+	if timeoutErr.Remaining != 1 {
+		t.Fatalf("got Remaining=%d, want 1", timeoutErr.Remaining)
+	}
+}
+
+func TestWriterCloseWithTimeoutSucceedsWhenWorkersFinish(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(2, &buf)
+
+	if _, err := w.Write([]byte("done\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.CloseWithTimeout(time.Second); err != nil {
+		t.Fatalf("CloseWithTimeout: %v", err)
+	}
+}
+
+// TestWriterWriteDoesNotRaceClose sends Write and Close concurrently,
+// many times over, to catch a Write losing the running check just
+// before Close closes the jobs channel -- which used to panic with
+// "send on closed channel" instead of cleanly returning
+// ErrWriterClosed.
+func TestWriterWriteDoesNotRaceClose(t *testing.T) {
+	for i := 0; i < 2000; i++ {
+		var buf bytes.Buffer
+		w := NewWriter(1, &buf)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = w.Write([]byte("x\n"))
+		}()
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		<-done
+	}
+}
+
+func TestWriterResetWhileRunning(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(1, &buf)
+	defer w.Close()
+
+	if err := w.Reset(1, &buf); err != ErrWriterRunning {
+		t.Fatalf("Reset while running: got %v, want %v", err, ErrWriterRunning)
+	}
+}
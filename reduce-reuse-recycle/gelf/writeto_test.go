@@ -0,0 +1,32 @@
+package gelf
+
+import (
+	"net"
+	"testing"
+)
+
+func TestClientWriteToOverridesDestination(t *testing.T) {
+	conn := &memConn{}
+	c := newClient(conn, testAddr, Config{DisableCompression: true})
+
+	tenantA := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 12201}
+	tenantB := &net.UDPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 12201}
+
+	if _, err := c.WriteTo(tenantA, []byte("for tenant A")); err != nil {
+		t.Fatalf("WriteTo tenantA: %v", err)
+	}
+	if _, err := c.WriteTo(tenantB, []byte("for tenant B")); err != nil {
+		t.Fatalf("WriteTo tenantB: %v", err)
+	}
+
+	if len(conn.destAddrs) != 2 || conn.destAddrs[0].String() != tenantA.String() || conn.destAddrs[1].String() != tenantB.String() {
+		t.Fatalf("got destinations %v, want [%s %s]", conn.destAddrs, tenantA, tenantB)
+	}
+}
+
+func TestClientWriteToRejectsIncompatibleAddr(t *testing.T) {
+	c := newClient(&memConn{}, testAddr, Config{})
+	if _, err := c.WriteTo(&net.TCPAddr{}, []byte("x")); err == nil {
+		t.Fatalf("expected an error for an incompatible address type")
+	}
+}